@@ -0,0 +1,184 @@
+package yfinance
+
+import (
+	"math"
+)
+
+// AutoAdjustPrices adjusts Open, High, Low and Close for dividends and
+// splits, and sets AdjClose to the adjusted Close.
+//
+// Every bar is scaled by the product of (1 - dividend / previous close)
+// over the dividends that went ex after it. Dividends after the last bar,
+// which Yahoo includes in AdjClose but does not report for the requested
+// range, are carried over from the AdjClose/Close ratio of the last bar.
+//
+// Volume is left alone on purpose: Yahoo already reports it split-adjusted,
+// and dividends do not change the number of shares traded. Back-adjusted
+// data is first adjusted for splits again, volumes included, so Volume is
+// split-adjusted either way.
+func (hr *HistoryResult) AutoAdjustPrices() {
+	if hr.AutoAdjusted || len(hr.Data) == 0 {
+		return
+	}
+
+	if hr.BackAdjusted {
+		splits := hr.splitFactors()
+		for i := range hr.Data {
+			hr.Data[i].scale(1/splits[i], splits[i])
+		}
+		hr.BackAdjusted = false
+	}
+
+	dividends := hr.dividendFactors()
+	for i := range hr.Data {
+		bar := &hr.Data[i]
+		bar.Open *= dividends[i]
+		bar.High *= dividends[i]
+		bar.Low *= dividends[i]
+		bar.Close *= dividends[i]
+		bar.AdjClose = bar.Close
+		if bar.IsMissing(FieldClose) {
+			bar.Missing |= FieldAdjClose
+		} else {
+			bar.Missing &^= FieldAdjClose
+		}
+	}
+	hr.AutoAdjusted = true
+}
+
+// BackAdjustPrices undoes Yahoo's split adjustment, so that Open, High,
+// Low, Close and Volume are the prices and volumes as traded. AdjClose
+// stays adjusted for splits and dividends. It has no effect on
+// auto-adjusted data.
+func (hr *HistoryResult) BackAdjustPrices() {
+	if hr.AutoAdjusted || hr.BackAdjusted || len(hr.Data) == 0 {
+		return
+	}
+
+	splits := hr.splitFactors()
+	for i := range hr.Data {
+		hr.Data[i].scale(splits[i], 1/splits[i])
+	}
+	hr.BackAdjusted = true
+}
+
+// scale multiplies the OHLC prices of the bar by price and its volume by
+// volume. AdjClose is left alone.
+func (p *PriceData) scale(price, volume float64) {
+	if price == 1 && volume == 1 {
+		return
+	}
+	p.Open *= price
+	p.High *= price
+	p.Low *= price
+	p.Close *= price
+	if !p.IsMissing(FieldVolume) {
+		p.Volume = int64(math.Round(float64(p.Volume) * volume))
+	}
+}
+
+// splitFactors returns, for every bar, the product of the ratios of the
+// splits after it. Multiplying a split-adjusted price by it gives the
+// price as traded.
+func (hr *HistoryResult) splitFactors() []float64 {
+	factors := make([]float64, len(hr.Data))
+	for i := range factors {
+		factors[i] = 1
+	}
+
+	for _, split := range hr.Splits {
+		if split.Numerator <= 0 || split.Denominator <= 0 {
+			continue
+		}
+		ratio := split.Numerator / split.Denominator
+		for i, bar := range hr.Data {
+			if bar.Date.Before(split.Date) {
+				factors[i] *= ratio
+			}
+		}
+	}
+	return factors
+}
+
+// dividendFactors returns, for every bar, the factor that adjusts its
+// split-adjusted prices for the dividends after it
+func (hr *HistoryResult) dividendFactors() []float64 {
+	factors := make([]float64, len(hr.Data))
+
+	// Dividends after the last bar are only known through AdjClose
+	tail := 1.0
+	for i := len(hr.Data) - 1; i >= 0; i-- {
+		bar := hr.Data[i]
+		if !bar.IsMissing(FieldClose|FieldAdjClose) && bar.Close > 0 && bar.AdjClose > 0 {
+			tail = bar.AdjClose / bar.Close
+			break
+		}
+	}
+	for i := range factors {
+		factors[i] = tail
+	}
+
+	// Dividends are reported split-adjusted, so compare them to
+	// split-adjusted closes
+	closes := make([]float64, len(hr.Data))
+	splits := []float64(nil)
+	if hr.BackAdjusted {
+		splits = hr.splitFactors()
+	}
+	for i, bar := range hr.Data {
+		closes[i] = bar.Close
+		if splits != nil {
+			closes[i] /= splits[i]
+		}
+	}
+
+	last := hr.Data[len(hr.Data)-1].Date
+	for _, div := range hr.Dividends {
+		// Dividends after the last bar are already part of the tail
+		if div.Amount <= 0 || div.Date.After(last) {
+			continue
+		}
+
+		// The previous close is the last reported close before the ex-date
+		prevClose := math.NaN()
+		for i, bar := range hr.Data {
+			if !bar.Date.Before(div.Date) {
+				break
+			}
+			if !bar.IsMissing(FieldClose) && closes[i] > 0 {
+				prevClose = closes[i]
+			}
+		}
+		if math.IsNaN(prevClose) || div.Amount >= prevClose {
+			continue
+		}
+
+		factor := 1 - div.Amount/prevClose
+		for i, bar := range hr.Data {
+			if bar.Date.Before(div.Date) {
+				factors[i] *= factor
+			}
+		}
+	}
+	return factors
+}
+
+// Round rounds Open, High, Low, Close and AdjClose to decimals places
+func (hr *HistoryResult) Round(decimals int) {
+	pow := math.Pow(10, float64(decimals))
+	round := func(v float64) float64 {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return v
+		}
+		return math.Round(v*pow) / pow
+	}
+
+	for i := range hr.Data {
+		bar := &hr.Data[i]
+		bar.Open = round(bar.Open)
+		bar.High = round(bar.High)
+		bar.Low = round(bar.Low)
+		bar.Close = round(bar.Close)
+		bar.AdjClose = round(bar.AdjClose)
+	}
+}
@@ -0,0 +1,261 @@
+package yfinance
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache stores raw response bodies. Implementations must be safe for
+// concurrent use.
+type Cache interface {
+	// Get returns the value stored under key, or false if it is missing or
+	// has expired
+	Get(key string) ([]byte, bool)
+	// Set stores value under key for ttl
+	Set(key string, value []byte, ttl time.Duration)
+}
+
+// CacheTTLs sets how long responses are cached per endpoint type.
+// A zero duration disables caching for that endpoint type.
+type CacheTTLs struct {
+	Quote        time.Duration            // /v7/finance/quote
+	QuoteSummary time.Duration            // /v10/finance/quoteSummary
+	Search       time.Duration            // /v1/finance/search and lookup
+	Chart        map[string]time.Duration // /v8/finance/chart, by interval
+	Default      time.Duration            // Any other endpoint
+}
+
+// DefaultCacheTTLs returns TTLs suited to dashboards that poll the same
+// symbols repeatedly
+func DefaultCacheTTLs() CacheTTLs {
+	return CacheTTLs{
+		Quote:        15 * time.Second,
+		QuoteSummary: time.Hour,
+		Search:       10 * time.Minute,
+		Chart: map[string]time.Duration{
+			"1m":  30 * time.Second,
+			"2m":  time.Minute,
+			"5m":  2 * time.Minute,
+			"15m": 5 * time.Minute,
+			"30m": 10 * time.Minute,
+			"60m": 15 * time.Minute,
+			"90m": 15 * time.Minute,
+			"1h":  15 * time.Minute,
+			"1d":  time.Hour,
+			"5d":  6 * time.Hour,
+			"1wk": 6 * time.Hour,
+			"1mo": 6 * time.Hour,
+			"3mo": 6 * time.Hour,
+		},
+	}
+}
+
+// ttl returns how long the response to a GET of endpoint may be cached
+func (c CacheTTLs) ttl(endpoint string, params map[string]string) time.Duration {
+	path := endpointPath(endpoint)
+	switch {
+	case strings.Contains(path, "/finance/quoteSummary/"):
+		return c.QuoteSummary
+	case strings.HasSuffix(path, "/finance/quote"):
+		return c.Quote
+	case strings.Contains(path, "/finance/chart/"):
+		return c.Chart[params["interval"]]
+	case strings.HasSuffix(path, "/finance/search"), strings.HasSuffix(path, "/finance/lookup"):
+		return c.Search
+	default:
+		return c.Default
+	}
+}
+
+// cacheKey identifies a GET request by host, path and params, ignoring the
+// crumb, so that sessions pointed at different endpoints never share entries
+func cacheKey(endpoint string, params map[string]string) string {
+	key := requestKey("GET", endpoint, params)
+	if u, err := url.Parse(endpoint); err == nil && u.Host != "" {
+		key = u.Host + " " + key
+	}
+	return key
+}
+
+// MemoryCache is an in-memory Cache that evicts the least recently used
+// entry once it is full
+type MemoryCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	ll         *list.List
+	items      map[string]*list.Element
+}
+
+// memoryEntry is a MemoryCache entry
+type memoryEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// NewMemoryCache creates a MemoryCache holding at most maxEntries entries
+// (0 = unbounded)
+func NewMemoryCache(maxEntries int) *MemoryCache {
+	return &MemoryCache{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+	}
+}
+
+// Get implements Cache
+func (c *MemoryCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*memoryEntry)
+	if time.Now().After(entry.expires) {
+		c.ll.Remove(el)
+		delete(c.items, key)
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	return entry.value, true
+}
+
+// Set implements Cache
+func (c *MemoryCache) Set(key string, value []byte, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := time.Now().Add(ttl)
+	if el, ok := c.items[key]; ok {
+		entry := el.Value.(*memoryEntry)
+		entry.value = value
+		entry.expires = expires
+		c.ll.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&memoryEntry{key: key, value: value, expires: expires})
+	if c.maxEntries > 0 && c.ll.Len() > c.maxEntries {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*memoryEntry).key)
+	}
+}
+
+// Len returns the number of entries in the cache, including expired ones
+// that have not been evicted yet
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// DiskCache is a Cache storing one file per entry, so cached responses
+// survive restarts
+type DiskCache struct {
+	dir string
+}
+
+// diskEntry is the on-disk format of a DiskCache entry
+type diskEntry struct {
+	Key     string    `json:"key"`
+	Expires time.Time `json:"expires"`
+	Value   []byte    `json:"value"`
+}
+
+// NewDiskCache creates a DiskCache in dir. An empty dir uses a "responses"
+// directory next to the cookie cache.
+func NewDiskCache(dir string) (*DiskCache, error) {
+	if dir == "" {
+		cacheDir := getCacheDir()
+		if cacheDir == "" {
+			return nil, errors.New("no cache directory available")
+		}
+		dir = filepath.Join(cacheDir, "responses")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &DiskCache{dir: dir}, nil
+}
+
+// Get implements Cache
+func (c *DiskCache) Get(key string) ([]byte, bool) {
+	path := c.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	var entry diskEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Key != key {
+		return nil, false
+	}
+	if time.Now().After(entry.Expires) {
+		os.Remove(path)
+		return nil, false
+	}
+	return entry.Value, true
+}
+
+// Set implements Cache
+func (c *DiskCache) Set(key string, value []byte, ttl time.Duration) {
+	data, err := json.Marshal(diskEntry{
+		Key:     key,
+		Expires: time.Now().Add(ttl),
+		Value:   value,
+	})
+	if err != nil {
+		return
+	}
+
+	// Write to a temporary file first so readers never see partial entries
+	tmp, err := os.CreateTemp(c.dir, "tmp-*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if err := os.Rename(tmp.Name(), c.path(key)); err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// Clear removes every entry from the cache
+func (c *DiskCache) Clear() error {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".json") {
+			if err := os.Remove(filepath.Join(c.dir, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// path returns the file holding key
+func (c *DiskCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
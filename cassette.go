@@ -0,0 +1,224 @@
+package yfinance
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// CassetteMode selects whether a Cassette records or replays traffic
+type CassetteMode int
+
+const (
+	// CassetteRecord writes every request and response to the cassette file
+	CassetteRecord CassetteMode = iota
+	// CassetteReplay serves responses from the cassette file without network access
+	CassetteReplay
+)
+
+// Interaction is a single recorded request and its response
+type Interaction struct {
+	Method      string            `json:"method"`
+	Path        string            `json:"path"`
+	Params      map[string]string `json:"params,omitempty"`
+	Status      int               `json:"status"`
+	ContentType string            `json:"contentType,omitempty"`
+	Body        string            `json:"body"`
+	// BodyEncoding is "base64" for bodies that are not valid UTF-8, such
+	// as compressed or binary payloads, and empty otherwise
+	BodyEncoding string `json:"bodyEncoding,omitempty"`
+}
+
+// setBody stores body, base64-encoded unless it is valid UTF-8
+func (in *Interaction) setBody(body []byte) {
+	if utf8.Valid(body) {
+		in.Body = string(body)
+		in.BodyEncoding = ""
+		return
+	}
+	in.Body = base64.StdEncoding.EncodeToString(body)
+	in.BodyEncoding = "base64"
+}
+
+// body returns the recorded body, decoded
+func (in *Interaction) body() ([]byte, error) {
+	if in.BodyEncoding == "base64" {
+		return base64.StdEncoding.DecodeString(in.Body)
+	}
+	return []byte(in.Body), nil
+}
+
+// Cassette records the traffic of a session to a file, or replays it.
+// Recorded interactions are kept in memory until Save or Close writes them.
+//
+// Requests are matched on method, path and query parameters, ignoring the
+// host and the crumb, so a cassette recorded against Yahoo can be replayed
+// against any endpoints. Identical requests are replayed in recorded order,
+// and the last response is repeated once they run out. Cookies and other
+// headers are never written, so cassettes can be attached to bug reports.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+
+	path   string
+	mode   CassetteMode
+	mu     sync.Mutex
+	played map[string]int
+}
+
+// NewCassette opens a cassette file. In record mode an existing file is
+// overwritten by Save or Close; in replay mode the file must exist.
+func NewCassette(path string, mode CassetteMode) (*Cassette, error) {
+	c := &Cassette{
+		path:   path,
+		mode:   mode,
+		played: make(map[string]int),
+	}
+
+	if mode == CassetteReplay {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, c); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// Mode returns whether the cassette records or replays
+func (c *Cassette) Mode() CassetteMode {
+	return c.mode
+}
+
+// Save writes the interactions recorded so far to the cassette file
+func (c *Cassette) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, data, 0644)
+}
+
+// Close saves a recording cassette. It has no effect in replay mode.
+func (c *Cassette) Close() error {
+	if c.mode != CassetteRecord {
+		return nil
+	}
+	return c.Save()
+}
+
+// record stores resp and returns an equivalent response with a fresh body
+func (c *Cassette) record(method, endpoint string, params map[string]string, resp *http.Response) (*http.Response, error) {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	in := Interaction{
+		Method:      method,
+		Path:        endpointPath(endpoint),
+		Params:      stripCrumb(params),
+		Status:      resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+	in.setBody(body)
+	c.Interactions = append(c.Interactions, in)
+
+	return resp, nil
+}
+
+// replay returns the recorded response for a request
+func (c *Cassette) replay(method, endpoint string, params map[string]string) (*http.Response, error) {
+	key := requestKey(method, endpoint, params)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var matches []int
+	for i, in := range c.Interactions {
+		if requestKey(in.Method, in.Path, in.Params) == key {
+			matches = append(matches, i)
+		}
+	}
+	if len(matches) == 0 {
+		return nil, NewYFCassetteMissError(key)
+	}
+
+	n := c.played[key]
+	if n >= len(matches) {
+		n = len(matches) - 1
+	}
+	c.played[key]++
+	in := c.Interactions[matches[n]]
+	body, err := in.body()
+	if err != nil {
+		return nil, err
+	}
+
+	req, _ := http.NewRequest(method, endpoint, nil)
+	header := make(http.Header)
+	if in.ContentType != "" {
+		header.Set("Content-Type", in.ContentType)
+	}
+	return &http.Response{
+		Status:        http.StatusText(in.Status),
+		StatusCode:    in.Status,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// requestKey identifies a request by method, path and normalized params,
+// ignoring the host and the crumb
+func requestKey(method, endpoint string, params map[string]string) string {
+	values := url.Values{}
+	for k, v := range stripCrumb(params) {
+		values.Set(k, v)
+	}
+	key := strings.ToUpper(method) + " " + endpointPath(endpoint)
+	if encoded := values.Encode(); encoded != "" {
+		key += "?" + encoded
+	}
+	return key
+}
+
+// endpointPath returns the path component of an endpoint URL
+func endpointPath(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return endpoint
+	}
+	return u.Path
+}
+
+// stripCrumb returns a copy of params without the crumb
+func stripCrumb(params map[string]string) map[string]string {
+	if len(params) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(params))
+	for k, v := range params {
+		if k != "crumb" {
+			out[k] = v
+		}
+	}
+	return out
+}
@@ -0,0 +1,208 @@
+package yfinance
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// historyChunk is one request of a chunked history fetch
+type historyChunk struct {
+	start time.Time
+	end   time.Time
+}
+
+// historyChunks splits the range requested by options into chunks Yahoo
+// accepts for the interval. It returns nil when a single request will do,
+// and a YFLookbackError when an explicit Start is before the interval's
+// lookback. Periods reaching further back, which calendar months and leap
+// days can make slightly longer than the lookback, are clamped to it.
+func historyChunks(symbol string, options *HistoryOptions, now time.Time) ([]historyChunk, error) {
+	limit, ok := IntervalLimits[options.Interval]
+	if !ok {
+		return nil, nil
+	}
+	earliest := now.Add(-limit.MaxLookback)
+
+	var start, end time.Time
+	clamped := false
+	switch {
+	case options.Start != nil:
+		start = *options.Start
+		end = now
+		if options.End != nil {
+			end = *options.End
+		}
+	case options.Period == "max":
+		// Intraday "max" is as much as Yahoo still serves
+		start = earliest.Add(time.Minute)
+		end = now
+	default:
+		var ok bool
+		if start, ok = periodStart(options.Period, now); !ok {
+			return nil, nil
+		}
+		end = now
+		if start.Before(earliest) {
+			start = earliest.Add(time.Minute)
+			clamped = true
+		}
+	}
+
+	if start.Before(earliest) {
+		return nil, NewYFLookbackError(symbol, options.Interval, start, limit.MaxLookback)
+	}
+
+	// Keep Yahoo's own handling of periods that fit in one request
+	if end.Sub(start) <= limit.MaxSpan && (options.Start != nil || (options.Period != "max" && !clamped)) {
+		return nil, nil
+	}
+
+	var chunks []historyChunk
+	for from := start; from.Before(end); from = from.Add(limit.MaxSpan) {
+		to := from.Add(limit.MaxSpan)
+		if to.After(end) {
+			to = end
+		}
+		chunks = append(chunks, historyChunk{start: from, end: to})
+	}
+	return chunks, nil
+}
+
+// periodStart returns the start of a period ending at now
+func periodStart(period string, now time.Time) (time.Time, bool) {
+	switch period {
+	case "1d":
+		return now.AddDate(0, 0, -1), true
+	case "5d":
+		return now.AddDate(0, 0, -5), true
+	case "1mo":
+		return now.AddDate(0, -1, 0), true
+	case "3mo":
+		return now.AddDate(0, -3, 0), true
+	case "6mo":
+		return now.AddDate(0, -6, 0), true
+	case "1y":
+		return now.AddDate(-1, 0, 0), true
+	case "2y":
+		return now.AddDate(-2, 0, 0), true
+	case "5y":
+		return now.AddDate(-5, 0, 0), true
+	case "10y":
+		return now.AddDate(-10, 0, 0), true
+	case "ytd":
+		return time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location()), true
+	default:
+		return time.Time{}, false
+	}
+}
+
+// fetchChunks fetches every chunk concurrently, within the session's
+// concurrency limit, and stitches the results together
+func (t *Ticker) fetchChunks(ctx context.Context, options *HistoryOptions, chunks []historyChunk) (*HistoryResult, chartMeta, error) {
+	parts := make([]*HistoryResult, len(chunks))
+	metas := make([]chartMeta, len(chunks))
+	errs := make([]error, len(chunks))
+
+	var wg sync.WaitGroup
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			chunkOptions := *options
+			chunkOptions.Period = ""
+			chunkOptions.Start = &chunk.start
+			chunkOptions.End = &chunk.end
+			parts[i], metas[i], errs[i] = t.fetchChart(ctx, &chunkOptions)
+		}()
+	}
+	wg.Wait()
+
+	// Chunks without bars, e.g. over a holiday, are not an error
+	var found []*HistoryResult
+	var meta chartMeta
+	for i, err := range errs {
+		var missing *YFPricesMissingError
+		switch {
+		case err == nil:
+			found = append(found, parts[i])
+			meta = metas[i]
+		case errors.As(err, &missing):
+		default:
+			return nil, chartMeta{}, err
+		}
+	}
+	if len(found) == 0 {
+		return nil, chartMeta{}, NewYFPricesMissingError(t.Symbol, "")
+	}
+
+	return mergeHistory(found), meta, nil
+}
+
+// mergeHistory stitches histories of consecutive ranges together. Bars and
+// events present in several parts are kept once, from the latest part.
+func mergeHistory(parts []*HistoryResult) *HistoryResult {
+	last := parts[len(parts)-1]
+	merged := &HistoryResult{
+		Meta:     last.Meta,
+		Timezone: last.Timezone,
+		Currency: last.Currency,
+		Exchange: last.Exchange,
+	}
+
+	bars := make(map[int64]PriceData)
+	dividends := make(map[int64]DividendData)
+	splits := make(map[int64]SplitData)
+	gains := make(map[int64]CapitalGainData)
+	for _, part := range parts {
+		for _, bar := range part.Data {
+			bars[bar.Date.Unix()] = bar
+		}
+		for _, div := range part.Dividends {
+			dividends[div.Date.Unix()] = div
+		}
+		for _, split := range part.Splits {
+			splits[split.Date.Unix()] = split
+		}
+		for _, gain := range part.CapitalGains {
+			gains[gain.Date.Unix()] = gain
+		}
+	}
+
+	for _, bar := range bars {
+		merged.Data = append(merged.Data, bar)
+	}
+	sort.Slice(merged.Data, func(i, j int) bool {
+		return merged.Data[i].Date.Before(merged.Data[j].Date)
+	})
+	for _, div := range dividends {
+		merged.Dividends = append(merged.Dividends, div)
+	}
+	for _, split := range splits {
+		merged.Splits = append(merged.Splits, split)
+	}
+	for _, gain := range gains {
+		merged.CapitalGains = append(merged.CapitalGains, gain)
+	}
+	merged.sortEvents()
+
+	// Keep the trading periods of every part, once per day
+	merged.Meta.TradingPeriods = nil
+	days := make(map[int64]bool)
+	for _, part := range parts {
+		for _, day := range part.Meta.TradingPeriods {
+			if start := day.Regular.Start.Unix(); !days[start] {
+				days[start] = true
+				merged.Meta.TradingPeriods = append(merged.Meta.TradingPeriods, day)
+			}
+		}
+	}
+	sort.Slice(merged.Meta.TradingPeriods, func(i, j int) bool {
+		return merged.Meta.TradingPeriods[i].Regular.Start.Before(merged.Meta.TradingPeriods[j].Regular.Start)
+	})
+
+	return merged
+}
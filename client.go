@@ -0,0 +1,307 @@
+package yfinance
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Client is an isolated Yahoo Finance session.
+//
+// Every Ticker, Tickers, Search, Lookup, Download and GetQuotes call made
+// through a Client shares its single YfData session (cookie jar, crumb and
+// transport), and its Streamers connect with the same proxy, endpoints and
+// user agent. Unlike the package-level helpers, a Client never reads
+// GlobalConfig, so several clients with different proxies, timeouts and
+// retry settings can live in the same process.
+type Client struct {
+	data *YfData
+}
+
+// ClientOption is a functional option for Client
+type ClientOption func(*clientConfig)
+
+// clientConfig holds the settings used to build a YfData session
+type clientConfig struct {
+	proxy       string
+	retries     int
+	retryPolicy RetryPolicy
+	timeout     time.Duration
+	userAgent   string
+	cacheDir    string
+	endpoints   Endpoints
+
+	transport  http.RoundTripper
+	doer       Doer
+	middleware []Middleware
+	cassette   *Cassette
+	limiter    *RateLimiter
+	cache      Cache
+	cacheTTLs  CacheTTLs
+
+	maxConcurrency int
+}
+
+// WithProxy sets the proxy URL used for every request of the client
+func WithProxy(proxy string) ClientOption {
+	return func(c *clientConfig) {
+		c.proxy = proxy
+	}
+}
+
+// WithRetries sets the number of retries for failed requests. Zero
+// disables retries. It has no effect when WithRetryPolicy is used.
+func WithRetries(retries int) ClientOption {
+	return func(c *clientConfig) {
+		c.retries = retries
+	}
+}
+
+// WithRetryPolicy replaces the default exponential backoff policy
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(c *clientConfig) {
+		c.retryPolicy = policy
+	}
+}
+
+// WithTimeout sets the per-request timeout
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(c *clientConfig) {
+		c.timeout = timeout
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+// By default a browser user agent is picked at random from UserAgents.
+func WithUserAgent(ua string) ClientOption {
+	return func(c *clientConfig) {
+		c.userAgent = ua
+	}
+}
+
+// WithCacheDir sets the directory used to persist the cookie and crumb.
+// Clients only share a cached crumb when their proxy, endpoints and user
+// agent match. An empty string disables the on-disk cache.
+func WithCacheDir(dir string) ClientOption {
+	return func(c *clientConfig) {
+		c.cacheDir = dir
+	}
+}
+
+// WithEndpoints overrides the Yahoo Finance base URLs, including the hosts
+// used for cookie, crumb and consent negotiation. Empty fields keep their
+// production values.
+func WithEndpoints(endpoints Endpoints) ClientOption {
+	return func(c *clientConfig) {
+		c.endpoints = endpoints.withDefaults()
+	}
+}
+
+// WithTransport replaces the default uTLS transport. The session still
+// manages cookies and timeouts around it. WithProxy has no effect when a
+// custom transport is set.
+func WithTransport(transport http.RoundTripper) ClientOption {
+	return func(c *clientConfig) {
+		c.transport = transport
+	}
+}
+
+// WithHTTPClient sets the Doer that executes every request, including the
+// cookie and crumb bootstrap. It takes precedence over WithTransport,
+// WithProxy and WithTimeout. An *http.Client without a Jar is given the
+// session's cookie jar.
+func WithHTTPClient(doer Doer) ClientOption {
+	return func(c *clientConfig) {
+		c.doer = doer
+	}
+}
+
+// WithMiddleware wraps the session's Doer. Middleware registered first
+// sees each request first.
+func WithMiddleware(middleware ...Middleware) ClientOption {
+	return func(c *clientConfig) {
+		c.middleware = append(c.middleware, middleware...)
+	}
+}
+
+// WithCassette records every request and response of the session to the
+// cassette, or serves them from it when the cassette is in replay mode.
+// In replay mode no network request is made, not even for the crumb.
+func WithCassette(cassette *Cassette) ClientOption {
+	return func(c *clientConfig) {
+		c.cassette = cassette
+	}
+}
+
+// WithRateLimiter throttles every request of the session with limiter.
+// Sharing one limiter between clients makes them share its budget.
+func WithRateLimiter(limiter *RateLimiter) ClientOption {
+	return func(c *clientConfig) {
+		c.limiter = limiter
+	}
+}
+
+// WithCache caches successful GET responses in cache, for as long as the
+// client's CacheTTLs allow. Sharing one cache between clients is safe.
+func WithCache(cache Cache) ClientOption {
+	return func(c *clientConfig) {
+		c.cache = cache
+	}
+}
+
+// WithCacheTTLs overrides DefaultCacheTTLs. It has no effect without
+// WithCache.
+func WithCacheTTLs(ttls CacheTTLs) ClientOption {
+	return func(c *clientConfig) {
+		c.cacheTTLs = ttls
+	}
+}
+
+// WithMaxConcurrency limits how many requests the session has in flight at
+// once, across every ticker, download and chunked history fetch. Zero
+// means no limit.
+func WithMaxConcurrency(n int) ClientOption {
+	return func(c *clientConfig) {
+		c.maxConcurrency = n
+	}
+}
+
+// defaultClientConfig returns the configuration used by NewClient before
+// options are applied
+func defaultClientConfig() clientConfig {
+	return clientConfig{
+		proxy:     proxyFromEnv(),
+		retries:   DefaultRetryPolicy().MaxRetries,
+		timeout:   30 * time.Second,
+		cacheDir:  getCacheDir(),
+		endpoints: DefaultEndpoints(),
+		cacheTTLs: DefaultCacheTTLs(),
+	}
+}
+
+// globalClientConfig returns a configuration snapshot of GlobalConfig, used
+// by the package-level helpers
+func globalClientConfig() clientConfig {
+	cfg := defaultClientConfig()
+	cfg.proxy = GlobalConfig.GetProxy()
+	cfg.retries = GlobalConfig.GetRetries()
+	cfg.limiter = GlobalConfig.GetRateLimiter()
+	cfg.cache = GlobalConfig.GetCache()
+	if timeout := GlobalConfig.GetTimeout(); timeout > 0 {
+		cfg.timeout = time.Duration(timeout) * time.Second
+	}
+	return cfg
+}
+
+// sharedSession is the session reused by GetQuotes and
+// GetQuotesWithOptions, so that repeated calls keep their cookie and crumb.
+// It is replaced when GlobalConfig changes.
+var sharedSession struct {
+	mu   sync.Mutex
+	cfg  clientConfig
+	data *YfData
+}
+
+// sharedYfData returns the shared session, creating it on first use or when
+// GlobalConfig no longer matches the settings it was created with
+func sharedYfData() *YfData {
+	cfg := globalClientConfig()
+
+	sharedSession.mu.Lock()
+	defer sharedSession.mu.Unlock()
+	if sharedSession.data == nil || !sameGlobalSettings(sharedSession.cfg, cfg) {
+		sharedSession.cfg = cfg
+		sharedSession.data = newYfData(cfg)
+	}
+	return sharedSession.data
+}
+
+// sameGlobalSettings reports whether a and b agree on every setting
+// globalClientConfig reads from GlobalConfig
+func sameGlobalSettings(a, b clientConfig) bool {
+	if a.proxy != b.proxy || a.retries != b.retries || a.timeout != b.timeout || a.limiter != b.limiter {
+		return false
+	}
+	if a.cache == nil || b.cache == nil {
+		return a.cache == b.cache
+	}
+	// Comparing interfaces holding uncomparable values panics
+	ta, tb := reflect.TypeOf(a.cache), reflect.TypeOf(b.cache)
+	return ta == tb && ta.Comparable() && a.cache == b.cache
+}
+
+// proxyFromEnv returns the proxy configured through environment variables
+func proxyFromEnv() string {
+	for _, key := range []string{"YFINANCE_PROXY", "HTTPS_PROXY", "HTTP_PROXY"} {
+		if proxy := os.Getenv(key); proxy != "" {
+			return proxy
+		}
+	}
+	return ""
+}
+
+// NewClient creates a new Client with its own session
+func NewClient(opts ...ClientOption) *Client {
+	cfg := defaultClientConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &Client{data: newYfData(cfg)}
+}
+
+// Data returns the session shared by everything created from the client
+func (c *Client) Data() *YfData {
+	return c.data
+}
+
+// Ticker creates a Ticker bound to the client's session
+func (c *Client) Ticker(symbol string) *Ticker {
+	return NewTickerWithData(symbol, c.data)
+}
+
+// Tickers creates a Tickers bound to the client's session
+func (c *Client) Tickers(symbols []string) *Tickers {
+	return newTickers(symbols, c.data)
+}
+
+// Search creates a Search bound to the client's session
+func (c *Client) Search(query string, opts ...SearchOption) *Search {
+	return newSearch(query, c.data, opts...)
+}
+
+// Lookup creates a Lookup bound to the client's session
+func (c *Client) Lookup(query string) *Lookup {
+	return newLookup(query, c.data)
+}
+
+// Download downloads historical data for multiple tickers using the
+// client's session
+func (c *Client) Download(ctx context.Context, options *DownloadOptions) (*DownloadResult, error) {
+	return download(ctx, c.data, options)
+}
+
+// DownloadStream downloads historical data for multiple tickers over the
+// client's session, sending each result as soon as it is done
+func (c *Client) DownloadStream(ctx context.Context, options *DownloadOptions) <-chan DownloadItem {
+	return downloadStream(ctx, c.data, options)
+}
+
+// Streamer creates a Streamer connecting with the client's proxy, endpoints
+// and user agent
+func (c *Client) Streamer(opts ...StreamerOption) *Streamer {
+	return newStreamer(c.data, opts...)
+}
+
+// GetQuotes fetches quotes for multiple tickers using the client's session
+func (c *Client) GetQuotes(ctx context.Context, symbols []string) ([]*Quote, error) {
+	return getQuotes(ctx, c.data, symbols)
+}
+
+// GetQuotesWithOptions fetches quotes for any number of tickers, in
+// parallel batches, using the client's session
+func (c *Client) GetQuotesWithOptions(ctx context.Context, symbols []string, options *QuotesOptions) (*QuotesResult, error) {
+	return getQuotesWithOptions(ctx, c.data, symbols, options)
+}
@@ -0,0 +1,65 @@
+package yfinance
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// flightGroup coalesces concurrent calls with the same key into a single
+// call whose result is shared by every caller
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flight
+}
+
+// flight is an in-flight or completed call
+type flight struct {
+	done chan struct{}
+	body []byte
+	err  error
+}
+
+// do runs fn once for all concurrent callers with the same key. Callers
+// stop waiting when their own context is done. If the shared call fails
+// because the context of the caller that started it was cancelled, the
+// remaining callers run fn again themselves.
+func (g *flightGroup) do(ctx context.Context, key string, fn func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flight)
+	}
+	if f, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+
+		select {
+		case <-f.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		if isContextError(f.err) && ctx.Err() == nil {
+			return fn(ctx)
+		}
+		return f.body, f.err
+	}
+
+	f := &flight{done: make(chan struct{})}
+	g.calls[key] = f
+	g.mu.Unlock()
+
+	f.body, f.err = fn(ctx)
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	close(f.done)
+
+	return f.body, f.err
+}
+
+// isContextError reports whether err was caused by a cancelled or expired
+// context
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
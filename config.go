@@ -1,7 +1,6 @@
 package yfinance
 
 import (
-	"os"
 	"sync"
 )
 
@@ -19,12 +18,18 @@ type Config struct {
 
 	// Request timeout in seconds
 	Timeout int
+
+	// RateLimiter shared by the package-level helpers (nil = unlimited)
+	RateLimiter *RateLimiter
+
+	// Response cache shared by the package-level helpers (nil = disabled)
+	Cache Cache
 }
 
 // GlobalConfig is the global configuration instance
 var GlobalConfig = &Config{
 	Proxy:          "",
-	Retries:        0,
+	Retries:        3,
 	HideExceptions: true,
 	Logging:        false,
 	Timeout:        30,
@@ -40,15 +45,8 @@ func (c *Config) SetProxy(proxy string) {
 // GetProxy gets the current proxy setting
 // Returns environment variable YFINANCE_PROXY if set, otherwise returns configured proxy
 func (c *Config) GetProxy() string {
-	// First check environment variable
-	if proxy := os.Getenv("YFINANCE_PROXY"); proxy != "" {
-		return proxy
-	}
-	// Then check HTTP_PROXY/HTTPS_PROXY
-	if proxy := os.Getenv("HTTPS_PROXY"); proxy != "" {
-		return proxy
-	}
-	if proxy := os.Getenv("HTTP_PROXY"); proxy != "" {
+	// First check environment variables
+	if proxy := proxyFromEnv(); proxy != "" {
 		return proxy
 	}
 	// Finally return configured proxy
@@ -99,6 +97,34 @@ func (c *Config) GetTimeout() int {
 	return c.Timeout
 }
 
+// SetRateLimiter sets the rate limiter shared by the package-level helpers
+func (c *Config) SetRateLimiter(limiter *RateLimiter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.RateLimiter = limiter
+}
+
+// GetRateLimiter gets the rate limiter shared by the package-level helpers
+func (c *Config) GetRateLimiter() *RateLimiter {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.RateLimiter
+}
+
+// SetCache sets the response cache shared by the package-level helpers
+func (c *Config) SetCache(cache Cache) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Cache = cache
+}
+
+// GetCache gets the response cache shared by the package-level helpers
+func (c *Config) GetCache() Cache {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Cache
+}
+
 // SetConfig is a convenience function to set multiple config options
 func SetConfig(proxy string, retries int, hideExceptions bool, timeout int) {
 	cfg := GlobalConfig
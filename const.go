@@ -2,13 +2,97 @@
 // based on the Python yfinance library
 package yfinance
 
+import (
+	"strings"
+	"time"
+)
+
 // Base URLs for Yahoo Finance API
 const (
 	Query1URL = "https://query1.finance.yahoo.com"
 	BaseURL   = "https://query2.finance.yahoo.com"
 	RootURL   = "https://finance.yahoo.com"
+
+	// StreamerURL is the WebSocket endpoint of Yahoo's live price streamer
+	StreamerURL = "wss://streamer.finance.yahoo.com/?version=2"
+)
+
+// Base URLs used for cookie, crumb and consent negotiation
+const (
+	CookieURL         = "https://fc.yahoo.com"
+	ConsentURL        = "https://guce.yahoo.com"
+	CollectConsentURL = "https://consent.yahoo.com"
 )
 
+// Endpoints holds the base URLs a session talks to
+type Endpoints struct {
+	Query1         string // Quote API and basic crumb (defaults to Query1URL)
+	Query2         string // Chart, search, quoteSummary and CSRF crumb (defaults to BaseURL)
+	Root           string // Website endpoints such as news (defaults to RootURL)
+	Cookie         string // Basic strategy cookie bootstrap (defaults to CookieURL)
+	Consent        string // Consent and copyConsent pages (defaults to ConsentURL)
+	CollectConsent string // Consent form submission (defaults to CollectConsentURL)
+	Streamer       string // WebSocket price streamer (defaults to StreamerURL)
+}
+
+// DefaultEndpoints returns the production Yahoo Finance endpoints
+func DefaultEndpoints() Endpoints {
+	return Endpoints{
+		Query1:         Query1URL,
+		Query2:         BaseURL,
+		Root:           RootURL,
+		Cookie:         CookieURL,
+		Consent:        ConsentURL,
+		CollectConsent: CollectConsentURL,
+		Streamer:       StreamerURL,
+	}
+}
+
+// LocalEndpoints points every endpoint at a single server, such as a mock
+// Yahoo Finance running on localhost. The cookie and consent hosts are
+// mapped to the /fc, /guce and /consent path prefixes so that they can be
+// told apart from the API routes. The streamer is served over WebSocket at
+// /streamer.
+func LocalEndpoints(baseURL string) Endpoints {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	return Endpoints{
+		Query1:         baseURL,
+		Query2:         baseURL,
+		Root:           baseURL,
+		Cookie:         baseURL + "/fc",
+		Consent:        baseURL + "/guce",
+		CollectConsent: baseURL + "/consent",
+		Streamer:       "ws" + strings.TrimPrefix(baseURL, "http") + "/streamer",
+	}
+}
+
+// withDefaults fills empty endpoints with their production values
+func (e Endpoints) withDefaults() Endpoints {
+	def := DefaultEndpoints()
+	if e.Query1 == "" {
+		e.Query1 = def.Query1
+	}
+	if e.Query2 == "" {
+		e.Query2 = def.Query2
+	}
+	if e.Root == "" {
+		e.Root = def.Root
+	}
+	if e.Cookie == "" {
+		e.Cookie = def.Cookie
+	}
+	if e.Consent == "" {
+		e.Consent = def.Consent
+	}
+	if e.CollectConsent == "" {
+		e.CollectConsent = def.CollectConsent
+	}
+	if e.Streamer == "" {
+		e.Streamer = def.Streamer
+	}
+	return e
+}
+
 // Valid periods for history
 var ValidPeriods = []string{
 	"1d", "5d", "1mo", "3mo", "6mo", "1y", "2y", "5y", "10y", "ytd", "max",
@@ -31,6 +115,33 @@ var IntradayIntervals = map[string]bool{
 	"1h":  true,
 }
 
+// IntervalLimit describes how much intraday history Yahoo serves
+type IntervalLimit struct {
+	MaxSpan     time.Duration // Longest range a single request may cover
+	MaxLookback time.Duration // How far back from now data is available
+}
+
+// IntervalLimits lists Yahoo's limits per intraday interval. History splits
+// longer ranges into chunks and rejects ranges beyond the lookback.
+//
+// 1m bars are often documented as limited to the last 7 days, but that is
+// the span of a single request: Yahoo serves 1m bars from the last 30 days
+// to requests of at most 7 days each, so the 30-day lookback is fetched in
+// 7-day chunks.
+var IntervalLimits = map[string]IntervalLimit{
+	"1m":  {MaxSpan: 7 * oneDay, MaxLookback: 30 * oneDay},
+	"2m":  {MaxSpan: 60 * oneDay, MaxLookback: 60 * oneDay},
+	"5m":  {MaxSpan: 60 * oneDay, MaxLookback: 60 * oneDay},
+	"15m": {MaxSpan: 60 * oneDay, MaxLookback: 60 * oneDay},
+	"30m": {MaxSpan: 60 * oneDay, MaxLookback: 60 * oneDay},
+	"60m": {MaxSpan: 730 * oneDay, MaxLookback: 730 * oneDay},
+	"90m": {MaxSpan: 60 * oneDay, MaxLookback: 60 * oneDay},
+	"1h":  {MaxSpan: 730 * oneDay, MaxLookback: 730 * oneDay},
+}
+
+// oneDay is the length of a calendar day, ignoring DST changes
+const oneDay = 24 * time.Hour
+
 // PriceColumnNames for history data
 var PriceColumnNames = []string{
 	"Open", "High", "Low", "Close", "Adj Close", "Volume",
@@ -4,11 +4,11 @@ import (
 	"bytes"
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
-	"math"
 	mrand "math/rand"
 	"net"
 	"net/http"
@@ -26,24 +26,50 @@ import (
 
 // CookieCache holds cached cookie data
 type CookieCache struct {
-	Cookie   string    `json:"cookie"`
-	Crumb    string    `json:"crumb"`
-	Expiry   time.Time `json:"expiry"`
-	Strategy string    `json:"strategy"`
+	Cookie    string    `json:"cookie"`
+	Crumb     string    `json:"crumb"`
+	Expiry    time.Time `json:"expiry"`
+	Strategy  string    `json:"strategy"`
+	UserAgent string    `json:"userAgent"` // User agent the crumb was issued to
 }
 
+// Doer executes HTTP requests. *http.Client satisfies it; custom
+// implementations can add middleware or replace the network entirely.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// DoerFunc adapts an ordinary function to the Doer interface
+type DoerFunc func(req *http.Request) (*http.Response, error)
+
+// Do calls f(req)
+func (f DoerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Middleware wraps a Doer with additional behaviour
+type Middleware func(next Doer) Doer
+
 // YfData handles HTTP communication with Yahoo Finance API
 type YfData struct {
-	client         *http.Client
+	client         Doer
 	jar            *cookiejar.Jar
 	crumb          string
 	cookie         string
 	cookieStrategy string
 	mu             sync.Mutex
 	userAgent      string
+	proxy          string
 	cacheDir       string
+	cacheKey       string
 	sessionID      string
-	transport      *utlsTransport
+	retryPolicy    RetryPolicy
+	endpoints      Endpoints
+	cassette       *Cassette
+	cache          Cache
+	cacheTTLs      CacheTTLs
+	flights        flightGroup
+	slots          chan struct{}
 }
 
 // utlsTransport is a custom transport that uses uTLS for TLS fingerprinting
@@ -157,8 +183,24 @@ func (t *utlsTransport) CloseIdleConnections() {
 	t.originalTransport.CloseIdleConnections()
 }
 
-// NewYfData creates a new YfData instance
+// NewYfData creates a new YfData instance configured from GlobalConfig
 func NewYfData() *YfData {
+	return newYfData(globalClientConfig())
+}
+
+// NewYfDataWithClient creates a new YfData instance with a custom HTTP client.
+// The client's Jar is replaced with the session's cookie jar.
+func NewYfDataWithClient(client *http.Client) *YfData {
+	client.Jar = nil
+	cfg := globalClientConfig()
+	cfg.doer = client
+	return newYfData(cfg)
+}
+
+// newYfData creates a new YfData instance from a client configuration.
+// Every request, including the cookie and crumb bootstrap, goes through the
+// configured Doer wrapped by the configured middleware.
+func newYfData(cfg clientConfig) *YfData {
 	jar, err := cookiejar.New(&cookiejar.Options{
 		PublicSuffixList: publicsuffix.List,
 	})
@@ -166,62 +208,74 @@ func NewYfData() *YfData {
 		jar, _ = cookiejar.New(nil)
 	}
 
-	// Generate session ID
-	b := make([]byte, 8)
-	rand.Read(b)
-	sessionID := hex.EncodeToString(b)
-
-	// Get proxy from config (which checks environment variables)
-	proxy := GlobalConfig.GetProxy()
-	transport := NewUtlsTransportWithProxy(proxy)
-
-	yd := &YfData{
-		jar:            jar,
-		cookieStrategy: "basic",
-		userAgent:      UserAgents[mrand.Intn(len(UserAgents))],
-		sessionID:      sessionID,
-		cacheDir:       getCacheDir(),
-		transport:      transport,
+	var client Doer
+	switch {
+	case cfg.doer != nil:
+		// Give plain http.Clients the session jar so cookies persist
+		if hc, ok := cfg.doer.(*http.Client); ok && hc.Jar == nil {
+			hc.Jar = jar
+		}
+		client = cfg.doer
+	default:
+		transport := cfg.transport
+		if transport == nil {
+			transport = NewUtlsTransportWithProxy(cfg.proxy)
+		}
+		client = &http.Client{
+			Timeout:   cfg.timeout,
+			Jar:       jar,
+			Transport: transport,
+		}
 	}
 
-	yd.client = &http.Client{
-		Timeout:   30 * time.Second,
-		Jar:       jar,
-		Transport: transport,
+	// The rate limiter sits closest to the network so that every attempt,
+	// including retries and the crumb bootstrap, draws a token
+	if cfg.limiter != nil {
+		client = cfg.limiter.Middleware()(client)
 	}
 
-	// Try to load cached cookie
-	yd.loadCookieCache()
+	// Apply middleware so that the first one registered is the outermost
+	for i := len(cfg.middleware) - 1; i >= 0; i-- {
+		client = cfg.middleware[i](client)
+	}
 
-	return yd
-}
+	retryPolicy := cfg.retryPolicy
+	if retryPolicy == nil {
+		policy := DefaultRetryPolicy()
+		policy.MaxRetries = cfg.retries
+		retryPolicy = policy
+	}
 
-// NewYfDataWithClient creates a new YfData instance with a custom HTTP client
-func NewYfDataWithClient(client *http.Client) *YfData {
-	jar, err := cookiejar.New(&cookiejar.Options{
-		PublicSuffixList: publicsuffix.List,
-	})
-	if err != nil {
-		jar, _ = cookiejar.New(nil)
+	if cfg.cacheDir != "" {
+		os.MkdirAll(cfg.cacheDir, 0755)
 	}
-	client.Jar = jar
 
 	// Generate session ID
 	b := make([]byte, 8)
 	rand.Read(b)
 
-	// Get proxy from config (which checks environment variables)
-	proxy := GlobalConfig.GetProxy()
-	transport := NewUtlsTransportWithProxy(proxy)
+	userAgent := cfg.userAgent
+	if userAgent == "" {
+		userAgent = UserAgents[mrand.Intn(len(UserAgents))]
+	}
 
 	yd := &YfData{
 		client:         client,
 		jar:            jar,
 		cookieStrategy: "basic",
-		userAgent:      UserAgents[mrand.Intn(len(UserAgents))],
+		userAgent:      userAgent,
+		proxy:          cfg.proxy,
 		sessionID:      hex.EncodeToString(b),
-		cacheDir:       getCacheDir(),
-		transport:      transport,
+		cacheDir:       cfg.cacheDir,
+		cacheKey:       cookieCacheKey(cfg),
+		retryPolicy:    retryPolicy,
+		endpoints:      cfg.endpoints,
+		cassette:       cfg.cassette,
+		cache:          cfg.cache,
+		cacheTTLs:      cfg.cacheTTLs,
+	}
+	if cfg.maxConcurrency > 0 {
+		yd.slots = make(chan struct{}, cfg.maxConcurrency)
 	}
 
 	// Try to load cached cookie
@@ -241,6 +295,24 @@ func getCacheDir() string {
 	return cacheDir
 }
 
+// cookieCacheKey identifies the sessions that may share a cached cookie
+// and crumb: those connecting through the same proxy to the same endpoints
+// with the same configured user agent. Sessions picking a random user
+// agent adopt the one the cached crumb was issued to.
+func cookieCacheKey(cfg clientConfig) string {
+	e := cfg.endpoints
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		cfg.proxy, cfg.userAgent,
+		e.Query1, e.Query2, e.Root, e.Cookie, e.Consent, e.CollectConsent,
+	}, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// cookieCacheFile returns the path of the session's cookie cache
+func (yd *YfData) cookieCacheFile() string {
+	return filepath.Join(yd.cacheDir, "cookie_cache_"+yd.cacheKey+".json")
+}
+
 // isValidCrumb checks if the crumb is valid (not an error page)
 func isValidCrumb(crumb string) bool {
 	if crumb == "" {
@@ -262,8 +334,7 @@ func (yd *YfData) loadCookieCache() bool {
 		return false
 	}
 
-	cacheFile := filepath.Join(yd.cacheDir, "cookie_cache.json")
-	data, err := os.ReadFile(cacheFile)
+	data, err := os.ReadFile(yd.cookieCacheFile())
 	if err != nil {
 		return false
 	}
@@ -286,6 +357,9 @@ func (yd *YfData) loadCookieCache() bool {
 	yd.cookie = cache.Cookie
 	yd.crumb = cache.Crumb
 	yd.cookieStrategy = cache.Strategy
+	if cache.UserAgent != "" {
+		yd.userAgent = cache.UserAgent
+	}
 	return true
 }
 
@@ -296,10 +370,11 @@ func (yd *YfData) saveCookieCache() error {
 	}
 
 	cache := CookieCache{
-		Cookie:   yd.cookie,
-		Crumb:    yd.crumb,
-		Expiry:   time.Now().Add(24 * time.Hour), // Cache for 24 hours
-		Strategy: yd.cookieStrategy,
+		Cookie:    yd.cookie,
+		Crumb:     yd.crumb,
+		Expiry:    time.Now().Add(24 * time.Hour), // Cache for 24 hours
+		Strategy:  yd.cookieStrategy,
+		UserAgent: yd.userAgent,
 	}
 
 	data, err := json.Marshal(cache)
@@ -307,8 +382,7 @@ func (yd *YfData) saveCookieCache() error {
 		return err
 	}
 
-	cacheFile := filepath.Join(yd.cacheDir, "cookie_cache.json")
-	return os.WriteFile(cacheFile, data, 0644)
+	return os.WriteFile(yd.cookieCacheFile(), data, 0644)
 }
 
 // SetUserAgent sets a custom user agent
@@ -328,71 +402,111 @@ func (yd *YfData) Post(ctx context.Context, endpoint string, params map[string]s
 	return yd.makeRequest(ctx, "POST", endpoint, params, body)
 }
 
-// makeRequest creates and executes an HTTP request with retry logic
+// makeRequest creates and executes an HTTP request, recording or replaying
+// it when the session has a cassette
 func (yd *YfData) makeRequest(ctx context.Context, method, endpoint string, params map[string]string, body interface{}) (*http.Response, error) {
-	var lastErr error
-	retries := GlobalConfig.GetRetries()
-	if retries == 0 {
-		retries = 3
+	if yd.cassette != nil && yd.cassette.mode == CassetteReplay {
+		return yd.cassette.replay(method, endpoint, params)
 	}
 
-	for attempt := 0; attempt <= retries; attempt++ {
-		resp, err := yd.doRequest(ctx, method, endpoint, params, body)
+	resp, err := yd.makeRequestWithRetry(ctx, method, endpoint, params, body)
+	if err != nil || yd.cassette == nil {
+		return resp, err
+	}
+	return yd.cassette.record(method, endpoint, params, resp)
+}
+
+// makeRequestWithRetry creates and executes an HTTP request, retrying
+// failures as decided by the session's RetryPolicy. Waits between attempts
+// end early when ctx is cancelled.
+func (yd *YfData) makeRequestWithRetry(ctx context.Context, method, endpoint string, params map[string]string, body interface{}) (*http.Response, error) {
+	start := time.Now()
+	consentAccepted := false
+
+	for attempt := 0; ; attempt++ {
+		release, err := yd.acquire(ctx)
 		if err != nil {
-			lastErr = err
-			if IsTransientError(err) && attempt < retries {
-				backoff := time.Duration(math.Pow(2, float64(attempt))) * time.Second
-				if backoff > 30*time.Second {
-					backoff = 30 * time.Second
+			return nil, err
+		}
+		resp, err := yd.doRequest(ctx, method, endpoint, params, body)
+		release()
+		if err == nil {
+			// Handle cookie consent redirect once, without counting it as a retry
+			if !consentAccepted && yd.isConsentURL(resp.Request.URL.String()) {
+				resp.Body.Close()
+				if err := yd.acceptConsent(ctx); err != nil {
+					return nil, err
 				}
-				time.Sleep(backoff)
+				consentAccepted = true
+				attempt--
 				continue
 			}
-			return nil, err
-		}
-
-		// Handle rate limiting with strategy switch
-		if resp.StatusCode == 429 {
-			resp.Body.Close()
-			lastErr = NewYFRateLimitError()
 
-			// Switch cookie strategy and retry
-			yd.switchCookieStrategy()
+			switch resp.StatusCode {
+			case http.StatusTooManyRequests:
+				// Switch cookie strategy before retrying
+				yd.switchCookieStrategy()
+				err = NewYFRateLimitError()
+			case http.StatusUnauthorized, http.StatusForbidden:
+				// Might need a new cookie
+				yd.ResetCrumb()
+				err = fmt.Errorf("authentication failed: %d", resp.StatusCode)
+			}
 
-			if attempt < retries {
-				backoff := time.Duration(math.Pow(2, float64(attempt))) * time.Second
-				if backoff > 30*time.Second {
-					backoff = 30 * time.Second
-				}
-				time.Sleep(backoff)
-				continue
+			if err == nil && resp.StatusCode < 400 {
+				return resp, nil
 			}
-			return nil, lastErr
 		}
 
-		// Handle cookie consent redirect
-		if yd.isConsentURL(resp.Request.URL.String()) {
-			resp.Body.Close()
-			if err := yd.acceptConsent(ctx); err != nil {
-				return nil, err
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			if resp != nil {
+				resp.Body.Close()
 			}
-			continue
+			return nil, ctxErr
 		}
 
-		// Handle 401/403 - might need new cookie
-		if resp.StatusCode == 401 || resp.StatusCode == 403 {
-			resp.Body.Close()
-			yd.ResetCrumb()
-			if attempt < retries {
-				continue
+		failed := RetryAttempt{
+			Attempt:  attempt,
+			Elapsed:  time.Since(start),
+			Response: resp,
+			Err:      err,
+		}
+		delay, retry := yd.retryPolicy.Backoff(failed)
+		if !retry {
+			if err == nil {
+				// Non-retryable HTTP error, let the caller inspect it
+				return resp, nil
+			}
+			if resp != nil {
+				resp.Body.Close()
 			}
-			return nil, fmt.Errorf("authentication failed: %d", resp.StatusCode)
+			return nil, err
 		}
 
-		return resp, nil
+		if observe := retryObserverFrom(ctx); observe != nil {
+			observe(failed, delay)
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, err
+		}
 	}
+}
 
-	return nil, lastErr
+// acquire waits for a request slot when the session limits the number of
+// concurrent requests, and returns the function that frees it
+func (yd *YfData) acquire(ctx context.Context) (func(), error) {
+	if yd.slots == nil {
+		return func() {}, nil
+	}
+	select {
+	case yd.slots <- struct{}{}:
+		return func() { <-yd.slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 }
 
 // switchCookieStrategy toggles between basic and csrf strategies
@@ -455,7 +569,16 @@ func (yd *YfData) doRequest(ctx context.Context, method, endpoint string, params
 	// Set browser-like headers (thread-safe)
 	yd.setBrowserHeadersSafe(req)
 
-	return yd.client.Do(req)
+	resp, err := yd.client.Do(req)
+	if err != nil {
+		return resp, err
+	}
+	// Injected Doers may not record the request, which the consent
+	// redirect check reads the final URL from
+	if resp.Request == nil {
+		resp.Request = req
+	}
+	return resp, nil
 }
 
 // setBrowserHeadersSafe sets headers with lock protection
@@ -547,7 +670,7 @@ func (yd *YfData) getCookieAndCrumbBasicInternal(ctx context.Context) (string, e
 
 // getCookieBasicInternal fetches cookie using basic strategy (must be called with lock held)
 func (yd *YfData) getCookieBasicInternal(ctx context.Context) error {
-	req, err := http.NewRequestWithContext(ctx, "GET", "https://fc.yahoo.com", nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", yd.endpoints.Cookie, nil)
 	if err != nil {
 		return err
 	}
@@ -568,9 +691,12 @@ func (yd *YfData) getCookieBasicInternal(ctx context.Context) error {
 		}
 	}
 
-	// Check jar for yahoo.com cookies
-	yahooURL, _ := url.Parse("https://yahoo.com")
-	for _, cookie := range yd.jar.Cookies(yahooURL) {
+	// Check jar for cookies set on the cookie host
+	cookieURL, err := url.Parse(yd.endpoints.Cookie)
+	if err != nil {
+		return nil
+	}
+	for _, cookie := range yd.jar.Cookies(cookieURL) {
 		if cookie.Name == "A3" {
 			yd.cookie = cookie.Value
 			return nil
@@ -582,7 +708,7 @@ func (yd *YfData) getCookieBasicInternal(ctx context.Context) error {
 
 // getCrumbBasicInternal fetches the crumb token (must be called with lock held)
 func (yd *YfData) getCrumbBasicInternal(ctx context.Context) (string, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", "https://query1.finance.yahoo.com/v1/test/getcrumb", nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", yd.endpoints.Query1+"/v1/test/getcrumb", nil)
 	if err != nil {
 		return "", err
 	}
@@ -626,7 +752,7 @@ func (yd *YfData) getCookieAndCrumbCSRFInternal(ctx context.Context) (string, er
 // getCookieCSRFInternal fetches cookie using CSRF/consent strategy (must be called with lock held)
 func (yd *YfData) getCookieCSRFInternal(ctx context.Context) error {
 	// Step 1: Get consent page
-	req, err := http.NewRequestWithContext(ctx, "GET", "https://guce.yahoo.com/consent", nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", yd.endpoints.Consent+"/consent", nil)
 	if err != nil {
 		return err
 	}
@@ -664,10 +790,10 @@ func (yd *YfData) getCookieCSRFInternal(ctx context.Context) error {
 	formData.Set("consentUUID", "default")
 	formData.Set("sessionId", sessionId)
 	formData.Set("csrfToken", csrfToken)
-	formData.Set("originalDoneUrl", "https://finance.yahoo.com/")
+	formData.Set("originalDoneUrl", yd.endpoints.Root+"/")
 	formData.Set("namespace", "yahoo")
 
-	consentURL := fmt.Sprintf("https://consent.yahoo.com/v2/collectConsent?sessionId=%s", sessionId)
+	consentURL := fmt.Sprintf("%s/v2/collectConsent?sessionId=%s", yd.endpoints.CollectConsent, sessionId)
 	req2, err := http.NewRequestWithContext(ctx, "POST", consentURL, strings.NewReader(formData.Encode()))
 	if err != nil {
 		return err
@@ -682,7 +808,7 @@ func (yd *YfData) getCookieCSRFInternal(ctx context.Context) error {
 	resp2.Body.Close()
 
 	// Step 3: Copy consent
-	copyURL := fmt.Sprintf("https://guce.yahoo.com/copyConsent?sessionId=%s", sessionId)
+	copyURL := fmt.Sprintf("%s/copyConsent?sessionId=%s", yd.endpoints.Consent, sessionId)
 	req3, err := http.NewRequestWithContext(ctx, "GET", copyURL, nil)
 	if err != nil {
 		return err
@@ -701,7 +827,7 @@ func (yd *YfData) getCookieCSRFInternal(ctx context.Context) error {
 
 // getCrumbCSRFInternal fetches crumb using query2 endpoint (must be called with lock held)
 func (yd *YfData) getCrumbCSRFInternal(ctx context.Context) (string, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", "https://query2.finance.yahoo.com/v1/test/getcrumb", nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", yd.endpoints.Query2+"/v1/test/getcrumb", nil)
 	if err != nil {
 		return "", err
 	}
@@ -771,7 +897,23 @@ func (yd *YfData) isConsentURL(urlStr string) bool {
 	if err != nil {
 		return false
 	}
-	return strings.HasSuffix(parsed.Hostname(), "consent.yahoo.com")
+	if strings.HasSuffix(parsed.Hostname(), "consent.yahoo.com") {
+		return true
+	}
+	return hasURLPrefix(parsed, yd.endpoints.Consent) || hasURLPrefix(parsed, yd.endpoints.CollectConsent)
+}
+
+// hasURLPrefix reports whether u lives under the base URL prefix
+func hasURLPrefix(u *url.URL, prefix string) bool {
+	base, err := url.Parse(prefix)
+	if err != nil || base.Host == "" {
+		return false
+	}
+	if u.Scheme != base.Scheme || u.Host != base.Host {
+		return false
+	}
+	basePath := strings.TrimSuffix(base.Path, "/")
+	return basePath == "" || u.Path == basePath || strings.HasPrefix(u.Path, basePath+"/")
 }
 
 // acceptConsent handles the consent form when redirected
@@ -787,33 +929,61 @@ func (yd *YfData) acceptConsent(ctx context.Context) error {
 	return nil
 }
 
-// GetRawJSON fetches and parses JSON from a URL
+// GetRawJSON fetches and parses JSON from a URL. Concurrent calls for the
+// same endpoint and params share a single request.
 func (yd *YfData) GetRawJSON(ctx context.Context, endpoint string, params map[string]string, v interface{}) error {
-	resp, err := yd.Get(ctx, endpoint, params)
+	key := cacheKey(endpoint, params)
+
+	var ttl time.Duration
+	if yd.cache != nil {
+		if ttl = yd.cacheTTLs.ttl(endpoint, params); ttl > 0 {
+			if body, ok := yd.cache.Get(key); ok && json.Unmarshal(body, v) == nil {
+				return nil
+			}
+		}
+	}
+
+	body, err := yd.flights.do(ctx, key, func(ctx context.Context) ([]byte, error) {
+		return yd.getRawBody(ctx, endpoint, params)
+	})
 	if err != nil {
 		return err
 	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to parse JSON: %w", err)
+	}
+
+	if ttl > 0 {
+		yd.cache.Set(key, body, ttl)
+	}
+
+	return nil
+}
+
+// getRawBody fetches the body of a successful GET request
+func (yd *YfData) getRawBody(ctx context.Context, endpoint string, params map[string]string) ([]byte, error) {
+	resp, err := yd.Get(ctx, endpoint, params)
+	if err != nil {
+		return nil, err
+	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
-		return fmt.Errorf("HTTP error: %d", resp.StatusCode)
+		return nil, fmt.Errorf("HTTP error: %d", resp.StatusCode)
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Check for Yahoo downtime message
 	if strings.Contains(string(body), "Will be right back") {
-		return NewYFDataException("*** YAHOO! FINANCE IS CURRENTLY DOWN! ***")
-	}
-
-	if err := json.Unmarshal(body, v); err != nil {
-		return fmt.Errorf("failed to parse JSON: %w", err)
+		return nil, NewYFDataException("*** YAHOO! FINANCE IS CURRENTLY DOWN! ***")
 	}
 
-	return nil
+	return body, nil
 }
 
 // ResetCrumb clears the cached crumb (useful when getting auth errors)
@@ -836,6 +1006,5 @@ func (yd *YfData) ClearCache() error {
 		return nil
 	}
 
-	cacheFile := filepath.Join(yd.cacheDir, "cookie_cache.json")
-	return os.Remove(cacheFile)
+	return os.Remove(yd.cookieCacheFile())
 }
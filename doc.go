@@ -50,9 +50,20 @@
 //	// Search for symbols
 //	quotes, err := yfinance.SearchSymbols(ctx, "Apple", yfinance.WithMaxResults(10))
 //
+// Clients:
+//
+//	// Create an isolated session with its own proxy, retries and timeout
+//	client := yfinance.NewClient(
+//	    yfinance.WithProxy("http://proxy:8080"),
+//	    yfinance.WithRetries(5),
+//	    yfinance.WithTimeout(60*time.Second),
+//	)
+//	ticker := client.Ticker("AAPL")
+//	quotes, err := client.GetQuotes(ctx, []string{"AAPL", "MSFT"})
+//
 // Configuration:
 //
-//	// Configure global settings
+//	// Configure global settings used by the package-level helpers
 //	yfinance.SetConfig("", 3, false, 30) // proxy, retries, hideExceptions, timeout
 //
 //	// Or use individual setters
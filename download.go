@@ -21,10 +21,31 @@ type DownloadOptions struct {
 	BackAdjust    bool
 	Repair        bool
 	KeepNaN       bool
+	Rounding      bool
+	Precision     *int // Decimal places used by Rounding instead of priceHint
 	Threads       int
-	Progress      bool
+	Progress      bool // Draw a progress bar on standard error
+	// CloseOnly fetches only Close, up to SparkBatchSize tickers per
+	// request. Spark carries no dividends, so it is only used with
+	// AutoAdjust off; downloads that need adjustment or full bars
+	// (AutoAdjust, BackAdjust, Repair, PrePost, Start/End or a chunked
+	// range) still fetch one chart per ticker.
+	CloseOnly     bool
 	ShowErrors    bool
 	Timeout       int
+
+	// OnProgress receives an event when a ticker starts, succeeds or
+	// fails, and when one of its requests is retried
+	OnProgress ProgressFunc
+	// OnTicker receives each ticker's result as soon as it is done, e.g.
+	// to persist it. Calls to OnProgress and OnTicker are serialized.
+	OnTicker func(DownloadItem)
+
+	// Location is the timezone of the Panel index (UTC if nil)
+	Location *time.Location
+	// NormalizeDates aligns the Panel on exchange-local dates, for daily
+	// bars of exchanges in different timezones
+	NormalizeDates bool
 }
 
 // DefaultDownloadOptions returns default download options
@@ -51,21 +72,80 @@ type DownloadResult struct {
 	Errors     map[string]error
 	Failed     []string
 	Succeeded  []string
+
+	// Panel aligns Data on one timestamp index, with columns ordered as
+	// DownloadOptions.GroupBy asks
+	Panel *Panel
 }
 
 // Download downloads historical data for multiple tickers
 func Download(ctx context.Context, options *DownloadOptions) (*DownloadResult, error) {
+	return download(ctx, NewYfData(), options)
+}
+
+// download downloads historical data for multiple tickers over a shared session
+func download(ctx context.Context, sharedData *YfData, options *DownloadOptions) (*DownloadResult, error) {
 	if options == nil {
 		options = DefaultDownloadOptions()
 	}
+	panelOptions := PanelOptions{
+		GroupBy:        options.GroupBy,
+		Location:       options.Location,
+		NormalizeDates: options.NormalizeDates,
+	}
+
+	// Check GroupBy before downloading anything
+	if _, err := NewPanel(nil, panelOptions); err != nil {
+		return nil, err
+	}
+
+	result := &DownloadResult{
+		Data:      make(map[string]*HistoryResult),
+		Errors:    make(map[string]error),
+		Failed:    []string{},
+		Succeeded: []string{},
+	}
+
+	for item := range downloadStream(ctx, sharedData, options) {
+		if item.Err != nil {
+			result.Errors[item.Ticker] = item.Err
+			result.Failed = append(result.Failed, item.Ticker)
+		} else {
+			result.Data[item.Ticker] = item.History
+			result.Succeeded = append(result.Succeeded, item.Ticker)
+		}
+	}
+
+	panel, err := NewPanel(result.Data, panelOptions)
+	if err != nil {
+		return nil, err
+	}
+	result.Panel = panel
+
+	return result, nil
+}
+
+// DownloadItem is the history of one downloaded ticker, or the error that
+// prevented fetching it
+type DownloadItem struct {
+	Ticker  string
+	History *HistoryResult
+	Err     error
+}
 
-	if len(options.Tickers) == 0 {
-		return &DownloadResult{
-			Data:      make(map[string]*HistoryResult),
-			Errors:    make(map[string]error),
-			Failed:    []string{},
-			Succeeded: []string{},
-		}, nil
+// DownloadStream downloads historical data for multiple tickers and sends
+// each ticker's result on the returned channel as soon as it is done. The
+// channel is closed once every ticker is done. It has room for every
+// ticker, so it may be abandoned; cancel ctx to stop pending fetches.
+func DownloadStream(ctx context.Context, options *DownloadOptions) <-chan DownloadItem {
+	return downloadStream(ctx, NewYfData(), options)
+}
+
+// downloadStream streams the download of multiple tickers over a shared
+// session
+func downloadStream(ctx context.Context, sharedData *YfData, options *DownloadOptions) <-chan DownloadItem {
+	if options == nil {
+		options = DefaultDownloadOptions()
 	}
 
 	// Normalize tickers
@@ -83,81 +163,109 @@ func Download(ctx context.Context, options *DownloadOptions) (*DownloadResult, e
 		}
 	}
 
-	result := &DownloadResult{
-		Data:      make(map[string]*HistoryResult),
-		Errors:    make(map[string]error),
-		Failed:    []string{},
-		Succeeded: []string{},
+	out := make(chan DownloadItem, len(tickers))
+	if len(tickers) == 0 {
+		close(out)
+		return out
 	}
 
-	// Create shared YfData for cookie/crumb sharing
-	sharedData := NewYfData()
+	// Create history options
+	histOpts := downloadHistoryOptions(options)
 
-	// Use mutex for thread-safe map access
-	var mu sync.Mutex
+	// Close-only downloads fetch batches of tickers from the spark
+	// endpoint; everything else fetches one chart per ticker
+	spark := useSpark(options, histOpts)
+	batchSize := 1
+	if spark {
+		batchSize = SparkBatchSize
+	}
+	var batches [][]string
+	for start := 0; start < len(tickers); start += batchSize {
+		batches = append(batches, tickers[start:min(start+batchSize, len(tickers))])
+	}
 
 	// Determine number of workers
 	workers := options.Threads
 	if workers <= 0 {
 		workers = 1
 	}
-	if workers > len(tickers) {
-		workers = len(tickers)
+	if workers > len(batches) {
+		workers = len(batches)
 	}
 
-	// Create ticker channel
-	tickerChan := make(chan string, len(tickers))
-	for _, t := range tickers {
-		tickerChan <- t
+	// Create batch channel
+	batchChan := make(chan []string, len(batches))
+	for _, batch := range batches {
+		batchChan <- batch
 	}
-	close(tickerChan)
+	close(batchChan)
 
 	// Create wait group
 	var wg sync.WaitGroup
 	wg.Add(workers)
 
+	progress := newProgressTracker(len(tickers), options)
+
 	// Start workers
 	for i := 0; i < workers; i++ {
 		go func() {
 			defer wg.Done()
-			for ticker := range tickerChan {
-				// Create ticker with shared data
-				t := NewTickerWithData(ticker, sharedData)
-
-				// Create history options
-				histOpts := &HistoryOptions{
-					Period:      options.Period,
-					Interval:    options.Interval,
-					Start:       options.Start,
-					End:         options.End,
-					PrePost:     options.PrePost,
-					AutoAdjust:  options.AutoAdjust,
-					BackAdjust:  options.BackAdjust,
-					Repair:      options.Repair,
-					KeepNaN:     options.KeepNaN,
-					Timeout:     options.Timeout,
+			for batch := range batchChan {
+				for _, ticker := range batch {
+					progress.report(ProgressEvent{Kind: ProgressStarted, Ticker: ticker})
 				}
 
-				// Fetch history
-				history, err := t.History(ctx, histOpts)
+				// Tickers missing from the spark response, or all of them if
+				// it failed, fall back to chart requests
+				var sparks map[string]*HistoryResult
+				if spark {
+					observed := WithRetryObserver(ctx, progress.retryObserver(strings.Join(batch, ",")))
+					sparks, _ = fetchSpark(observed, sharedData, batch, histOpts)
+				}
 
-				mu.Lock()
-				if err != nil {
-					result.Errors[ticker] = err
-					result.Failed = append(result.Failed, ticker)
-				} else {
-					result.Data[ticker] = history
-					result.Succeeded = append(result.Succeeded, ticker)
+				for _, ticker := range batch {
+					item := DownloadItem{Ticker: ticker}
+					if history, ok := sparks[ticker]; ok {
+						item.History = history
+					} else {
+						// Create ticker with shared data
+						t := NewTickerWithData(ticker, sharedData)
+						item.History, item.Err = t.History(WithRetryObserver(ctx, progress.retryObserver(ticker)), histOpts)
+					}
+					progress.finish(item)
+					out <- item
 				}
-				mu.Unlock()
 			}
 		}()
 	}
 
-	// Wait for all workers to complete
-	wg.Wait()
+	// Close the stream once all workers are done
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
 
-	return result, nil
+	return out
+}
+
+// downloadHistoryOptions returns the options each ticker of a download is
+// fetched with
+func downloadHistoryOptions(options *DownloadOptions) *HistoryOptions {
+	histOpts := &HistoryOptions{
+		Period:      options.Period,
+		Interval:    options.Interval,
+		Start:       options.Start,
+		End:         options.End,
+		PrePost:     options.PrePost,
+		AutoAdjust:  options.AutoAdjust,
+		BackAdjust:  options.BackAdjust,
+		Repair:      options.Repair,
+		KeepNaN:     options.KeepNaN,
+		Rounding:    options.Rounding,
+		Precision:   options.Precision,
+		Timeout:     options.Timeout,
+	}
+	return histOpts
 }
 
 // DownloadSimple is a simplified download function for common use cases
@@ -187,6 +295,11 @@ type Tickers struct {
 
 // NewTickers creates a new Tickers instance
 func NewTickers(symbols []string) *Tickers {
+	return newTickers(symbols, NewYfData())
+}
+
+// newTickers creates a new Tickers instance using the given session
+func newTickers(symbols []string, data *YfData) *Tickers {
 	// Normalize symbols
 	normalized := make([]string, 0, len(symbols))
 	seen := make(map[string]bool)
@@ -200,7 +313,7 @@ func NewTickers(symbols []string) *Tickers {
 
 	return &Tickers{
 		Symbols: normalized,
-		data:    NewYfData(),
+		data:    data,
 	}
 }
 
@@ -240,7 +353,7 @@ func (t *Tickers) History(ctx context.Context, options *HistoryOptions) (map[str
 
 // Quotes fetches quotes for all tickers
 func (t *Tickers) Quotes(ctx context.Context) ([]*Quote, error) {
-	return GetQuotes(ctx, t.Symbols)
+	return getQuotes(ctx, t.data, t.Symbols)
 }
 
 // String returns the string representation
@@ -1,6 +1,9 @@
 package yfinance
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 // YFException is the base exception for yfinance errors
 type YFException struct {
@@ -129,6 +132,30 @@ func NewYFInvalidPeriodError(ticker string, invalidPeriod string, validRanges []
 	}
 }
 
+// YFLookbackError represents a history request reaching further back than
+// Yahoo serves data for the interval
+type YFLookbackError struct {
+	Ticker   string
+	Interval string
+	Start    time.Time
+	Lookback time.Duration
+}
+
+func (e *YFLookbackError) Error() string {
+	return fmt.Sprintf("%s: %s data is only available for the last %d days, requested from %s",
+		e.Ticker, e.Interval, int(e.Lookback.Hours()/24), e.Start.Format("2006-01-02"))
+}
+
+// NewYFLookbackError creates a new YFLookbackError
+func NewYFLookbackError(ticker, interval string, start time.Time, lookback time.Duration) *YFLookbackError {
+	return &YFLookbackError{
+		Ticker:   ticker,
+		Interval: interval,
+		Start:    start,
+		Lookback: lookback,
+	}
+}
+
 // YFRateLimitError represents rate limiting errors
 type YFRateLimitError struct{}
 
@@ -141,12 +168,29 @@ func NewYFRateLimitError() *YFRateLimitError {
 	return &YFRateLimitError{}
 }
 
+// YFCassetteMissError represents a request missing from a replayed cassette
+type YFCassetteMissError struct {
+	Request string
+}
+
+func (e *YFCassetteMissError) Error() string {
+	return fmt.Sprintf("no recorded interaction for %s", e.Request)
+}
+
+// NewYFCassetteMissError creates a new YFCassetteMissError
+func NewYFCassetteMissError(request string) *YFCassetteMissError {
+	return &YFCassetteMissError{Request: request}
+}
+
 // IsTransientError checks if an error is transient and should be retried
 func IsTransientError(err error) bool {
 	if err == nil {
 		return false
 	}
-	// In Go, we check for network timeout errors
-	// This is a simplified check
+	// Cancellation is deliberate and must not be retried
+	if isContextError(err) {
+		return false
+	}
+	// Any other network error is treated as transient
 	return true
 }
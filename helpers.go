@@ -74,6 +74,15 @@ func getInt64(m map[string]interface{}, key string) int64 {
 	return 0
 }
 
+func getBool(m map[string]interface{}, key string) bool {
+	if v, ok := m[key]; ok {
+		if b, ok := v.(bool); ok {
+			return b
+		}
+	}
+	return false
+}
+
 func getTime(m map[string]interface{}, key string) time.Time {
 	if v, ok := m[key]; ok {
 		switch val := v.(type) {
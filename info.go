@@ -114,7 +114,7 @@ func (t *Ticker) GetInfo(ctx context.Context) (*Info, error) {
 		"financialData",
 	}
 
-	endpoint := fmt.Sprintf("%s/v10/finance/quoteSummary/%s", BaseURL, t.Symbol)
+	endpoint := fmt.Sprintf("%s/v10/finance/quoteSummary/%s", t.data.endpoints.Query2, t.Symbol)
 	params := map[string]string{
 		"modules": joinModules(modules),
 	}
@@ -286,7 +286,7 @@ func (t *Ticker) GetNews(ctx context.Context, count int) ([]News, error) {
 	}
 
 	// Use the XHR endpoint for news
-	endpoint := fmt.Sprintf("%s/xhr/ncp", RootURL)
+	endpoint := fmt.Sprintf("%s/xhr/ncp", t.data.endpoints.Root)
 	params := map[string]string{
 		"queryRef":   "latestNews",
 		"serviceKey": "ncp_fin",
@@ -380,7 +380,7 @@ type Calendar struct {
 
 // GetCalendar fetches calendar events for the ticker
 func (t *Ticker) GetCalendar(ctx context.Context) (*Calendar, error) {
-	endpoint := fmt.Sprintf("%s/v10/finance/quoteSummary/%s", BaseURL, t.Symbol)
+	endpoint := fmt.Sprintf("%s/v10/finance/quoteSummary/%s", t.data.endpoints.Query2, t.Symbol)
 	params := map[string]string{
 		"modules": "calendarEvents",
 	}
@@ -456,7 +456,7 @@ type Recommendation struct {
 
 // GetRecommendations fetches analyst recommendations for the ticker
 func (t *Ticker) GetRecommendations(ctx context.Context) ([]Recommendation, error) {
-	endpoint := fmt.Sprintf("%s/v10/finance/quoteSummary/%s", BaseURL, t.Symbol)
+	endpoint := fmt.Sprintf("%s/v10/finance/quoteSummary/%s", t.data.endpoints.Query2, t.Symbol)
 	params := map[string]string{
 		"modules": "recommendationTrend",
 	}
@@ -0,0 +1,208 @@
+package yfinance
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// PanelFields are the fields of every ticker in a Panel, in column order
+var PanelFields = []PriceField{FieldOpen, FieldHigh, FieldLow, FieldClose, FieldAdjClose, FieldVolume}
+
+// PanelOptions configures how NewPanel aligns histories
+type PanelOptions struct {
+	// GroupBy orders the columns by "column" (field, then ticker, the
+	// default) or by "ticker" (ticker, then field)
+	GroupBy string
+	// Location is the timezone of the index. It defaults to UTC.
+	Location *time.Location
+	// NormalizeDates keys every bar by its date on its exchange, at
+	// midnight in Location, so that daily bars of exchanges in different
+	// timezones share a row. Intraday bars of one day then collapse into
+	// the last one.
+	NormalizeDates bool
+}
+
+// Panel aligns the histories of several tickers on the union of their
+// timestamps, like a wide data frame with a (field, ticker) column index
+type Panel struct {
+	Index   []time.Time
+	Tickers []string
+	GroupBy string
+
+	columns map[panelKey]*PanelColumn
+}
+
+// panelKey identifies a column of a Panel
+type panelKey struct {
+	field  PriceField
+	ticker string
+}
+
+// PanelColumn is one field of one ticker, aligned on the panel index
+type PanelColumn struct {
+	Field  PriceField
+	Ticker string
+	// Values holds the field per row, NaN where it is missing
+	Values []float64
+	// Missing is set for rows where the ticker has no bar, or its bar did
+	// not report the field
+	Missing []bool
+}
+
+// NewPanel aligns histories, keyed by ticker, into a Panel
+func NewPanel(histories map[string]*HistoryResult, options PanelOptions) (*Panel, error) {
+	switch options.GroupBy {
+	case "":
+		options.GroupBy = "column"
+	case "column", "ticker":
+	default:
+		return nil, fmt.Errorf("invalid group by: %s, must be column or ticker", options.GroupBy)
+	}
+	loc := options.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	key := func(t time.Time) time.Time {
+		if options.NormalizeDates {
+			year, month, day := t.Date()
+			return time.Date(year, month, day, 0, 0, 0, 0, loc)
+		}
+		return t.In(loc)
+	}
+
+	p := &Panel{
+		GroupBy: options.GroupBy,
+		columns: make(map[panelKey]*PanelColumn),
+	}
+	for ticker, history := range histories {
+		if history != nil {
+			p.Tickers = append(p.Tickers, ticker)
+		}
+	}
+	sort.Strings(p.Tickers)
+
+	// Build the union index
+	rows := make(map[int64]time.Time)
+	for _, ticker := range p.Tickers {
+		for _, bar := range histories[ticker].Data {
+			k := key(bar.Date)
+			rows[k.UnixNano()] = k
+		}
+	}
+	for _, t := range rows {
+		p.Index = append(p.Index, t)
+	}
+	sort.Slice(p.Index, func(i, j int) bool { return p.Index[i].Before(p.Index[j]) })
+	row := make(map[int64]int, len(p.Index))
+	for i, t := range p.Index {
+		row[t.UnixNano()] = i
+	}
+
+	for _, ticker := range p.Tickers {
+		for _, field := range PanelFields {
+			column := &PanelColumn{
+				Field:   field,
+				Ticker:  ticker,
+				Values:  make([]float64, len(p.Index)),
+				Missing: make([]bool, len(p.Index)),
+			}
+			for i := range column.Values {
+				column.Values[i] = math.NaN()
+				column.Missing[i] = true
+			}
+			p.columns[panelKey{field, ticker}] = column
+		}
+
+		for _, bar := range histories[ticker].Data {
+			i := row[key(bar.Date).UnixNano()]
+			for _, field := range PanelFields {
+				column := p.columns[panelKey{field, ticker}]
+				if bar.IsMissing(field) {
+					column.Values[i] = math.NaN()
+					column.Missing[i] = true
+					continue
+				}
+				column.Values[i] = bar.value(field)
+				column.Missing[i] = false
+			}
+		}
+	}
+
+	return p, nil
+}
+
+// value returns a single field of the bar
+func (p PriceData) value(field PriceField) float64 {
+	switch field {
+	case FieldOpen:
+		return p.Open
+	case FieldHigh:
+		return p.High
+	case FieldLow:
+		return p.Low
+	case FieldClose:
+		return p.Close
+	case FieldAdjClose:
+		return p.AdjClose
+	case FieldVolume:
+		return float64(p.Volume)
+	default:
+		return math.NaN()
+	}
+}
+
+// Len returns the number of rows
+func (p *Panel) Len() int {
+	return len(p.Index)
+}
+
+// Column returns the column of field for ticker, or nil if the panel has no
+// such column
+func (p *Panel) Column(field PriceField, ticker string) *PanelColumn {
+	return p.columns[panelKey{field, ticker}]
+}
+
+// Columns returns every column, ordered by field then ticker when grouped
+// by "column", or by ticker then field when grouped by "ticker"
+func (p *Panel) Columns() []*PanelColumn {
+	columns := make([]*PanelColumn, 0, len(p.columns))
+	if p.GroupBy == "ticker" {
+		for _, ticker := range p.Tickers {
+			for _, field := range PanelFields {
+				columns = append(columns, p.columns[panelKey{field, ticker}])
+			}
+		}
+		return columns
+	}
+	for _, field := range PanelFields {
+		for _, ticker := range p.Tickers {
+			columns = append(columns, p.columns[panelKey{field, ticker}])
+		}
+	}
+	return columns
+}
+
+// Field returns the columns of field, one per ticker
+func (p *Panel) Field(field PriceField) []*PanelColumn {
+	columns := make([]*PanelColumn, 0, len(p.Tickers))
+	for _, ticker := range p.Tickers {
+		columns = append(columns, p.columns[panelKey{field, ticker}])
+	}
+	return columns
+}
+
+// Ticker returns the columns of ticker, one per field, or nil if the panel
+// does not hold it
+func (p *Panel) Ticker(ticker string) []*PanelColumn {
+	if p.columns[panelKey{FieldClose, ticker}] == nil {
+		return nil
+	}
+	columns := make([]*PanelColumn, 0, len(PanelFields))
+	for _, field := range PanelFields {
+		columns = append(columns, p.columns[panelKey{field, ticker}])
+	}
+	return columns
+}
@@ -0,0 +1,245 @@
+package yfinance
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tick is a live price update pushed by Yahoo's streamer. Fields the
+// update does not carry keep their zero value.
+type Tick struct {
+	Symbol      string
+	Price       float64
+	Time        time.Time
+	Currency    string
+	Exchange    string
+	QuoteType   string  // EQUITY, ETF, CRYPTOCURRENCY..., like Quote.QuoteType
+	MarketHours Session // Session the update was traded in
+
+	Change        float64
+	ChangePercent float64
+	DayVolume     int64
+	DayHigh       float64
+	DayLow        float64
+	Open          float64
+	PreviousClose float64
+	ShortName     string
+	LastSize      int64
+	PriceHint     int64
+
+	Bid     float64
+	BidSize int64
+	Ask     float64
+	AskSize int64
+
+	// Options
+	ExpireDate       time.Time
+	StrikePrice      float64
+	UnderlyingSymbol string
+	OpenInterest     int64
+
+	// Crypto
+	Volume24Hr          int64
+	VolumeAllCurrencies int64
+	FromCurrency        string
+	LastMarket          string
+	CirculatingSupply   float64
+	MarketCap           float64
+}
+
+// streamerQuoteTypes maps the quote type enumeration of the streamer to the
+// names used by the quote endpoint
+var streamerQuoteTypes = map[uint64]string{
+	5:    "ALTSYMBOL",
+	7:    "HEARTBEAT",
+	8:    "EQUITY",
+	9:    "INDEX",
+	11:   "MUTUALFUND",
+	12:   "MONEYMARKET",
+	13:   "OPTION",
+	14:   "CURRENCY",
+	15:   "WARRANT",
+	17:   "BOND",
+	18:   "FUTURE",
+	20:   "ETF",
+	23:   "COMMODITY",
+	28:   "ECNQUOTE",
+	41:   "CRYPTOCURRENCY",
+	42:   "INDICATOR",
+	1000: "INDUSTRY",
+}
+
+// streamerSessions maps the market hours enumeration of the streamer, whose
+// zero value is pre-market, to Session
+var streamerSessions = []Session{SessionPre, SessionRegular, SessionPost, SessionOvernight}
+
+// Protobuf wire types used by PricingData
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errTruncatedMessage = errors.New("truncated pricing message")
+
+// parseStreamerMessage decodes a text frame of the streamer. Version 2 of
+// the protocol wraps the base64 message in a JSON envelope, version 1 sends
+// it bare.
+func parseStreamerMessage(frame string) (Tick, error) {
+	frame = strings.TrimSpace(frame)
+	if strings.HasPrefix(frame, "{") {
+		var envelope struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		}
+		if err := json.Unmarshal([]byte(frame), &envelope); err != nil {
+			return Tick{}, err
+		}
+		// Other message types carry no price; a tick without a symbol is
+		// dropped by the streamer
+		if envelope.Type != "" && envelope.Type != "pricing" {
+			return Tick{}, nil
+		}
+		frame = envelope.Message
+	}
+
+	data, err := base64.StdEncoding.DecodeString(frame)
+	if err != nil {
+		return Tick{}, err
+	}
+	return decodePricingData(data)
+}
+
+// decodePricingData decodes a PricingData protobuf message. Unknown fields
+// are skipped.
+func decodePricingData(data []byte) (Tick, error) {
+	var tick Tick
+	var marketHours uint64
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return Tick{}, errTruncatedMessage
+		}
+		data = data[n:]
+		field, wire := key>>3, key&7
+
+		var (
+			varint uint64
+			fixed  uint64
+			bytes  []byte
+		)
+		switch wire {
+		case wireVarint:
+			if varint, n = binary.Uvarint(data); n <= 0 {
+				return Tick{}, errTruncatedMessage
+			}
+			data = data[n:]
+		case wireFixed64:
+			if len(data) < 8 {
+				return Tick{}, errTruncatedMessage
+			}
+			fixed, data = binary.LittleEndian.Uint64(data), data[8:]
+		case wireFixed32:
+			if len(data) < 4 {
+				return Tick{}, errTruncatedMessage
+			}
+			fixed, data = uint64(binary.LittleEndian.Uint32(data)), data[4:]
+		case wireBytes:
+			size, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < size {
+				return Tick{}, errTruncatedMessage
+			}
+			bytes, data = data[n:n+int(size)], data[n+int(size):]
+		default:
+			return Tick{}, fmt.Errorf("unsupported wire type %d in pricing message", wire)
+		}
+
+		sint := int64(varint>>1) ^ -int64(varint&1)
+		float := float32Value(uint32(fixed))
+		switch field {
+		case 1:
+			tick.Symbol = string(bytes)
+		case 2:
+			tick.Price = float
+		case 3:
+			tick.Time = time.UnixMilli(sint)
+		case 4:
+			tick.Currency = string(bytes)
+		case 5:
+			tick.Exchange = string(bytes)
+		case 6:
+			tick.QuoteType = streamerQuoteTypes[varint]
+		case 7:
+			marketHours = varint
+		case 8:
+			tick.ChangePercent = float
+		case 9:
+			tick.DayVolume = sint
+		case 10:
+			tick.DayHigh = float
+		case 11:
+			tick.DayLow = float
+		case 12:
+			tick.Change = float
+		case 13:
+			tick.ShortName = string(bytes)
+		case 14:
+			tick.ExpireDate = time.Unix(sint, 0)
+		case 15:
+			tick.Open = float
+		case 16:
+			tick.PreviousClose = float
+		case 17:
+			tick.StrikePrice = float
+		case 18:
+			tick.UnderlyingSymbol = string(bytes)
+		case 19:
+			tick.OpenInterest = sint
+		case 22:
+			tick.LastSize = sint
+		case 23:
+			tick.Bid = float
+		case 24:
+			tick.BidSize = sint
+		case 25:
+			tick.Ask = float
+		case 26:
+			tick.AskSize = sint
+		case 27:
+			tick.PriceHint = sint
+		case 28:
+			tick.Volume24Hr = sint
+		case 29:
+			tick.VolumeAllCurrencies = sint
+		case 30:
+			tick.FromCurrency = string(bytes)
+		case 31:
+			tick.LastMarket = string(bytes)
+		case 32:
+			tick.CirculatingSupply = math.Float64frombits(fixed)
+		case 33:
+			tick.MarketCap = math.Float64frombits(fixed)
+		}
+	}
+
+	if marketHours < uint64(len(streamerSessions)) {
+		tick.MarketHours = streamerSessions[marketHours]
+	}
+	return tick, nil
+}
+
+// float32Value converts the bits of a float field to float64, keeping the
+// shortest decimal form of the float32 so that 183.05 does not turn into
+// 183.0500030517578
+func float32Value(bits uint32) float64 {
+	f, _ := strconv.ParseFloat(strconv.FormatFloat(float64(math.Float32frombits(bits)), 'g', -1, 32), 64)
+	return f
+}
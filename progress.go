@@ -0,0 +1,194 @@
+package yfinance
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProgressKind is the kind of a ProgressEvent
+type ProgressKind uint8
+
+const (
+	// ProgressStarted is sent when the download of a ticker starts
+	ProgressStarted ProgressKind = iota
+	// ProgressSucceeded is sent when a ticker is downloaded
+	ProgressSucceeded
+	// ProgressFailed is sent when a ticker could not be downloaded
+	ProgressFailed
+	// ProgressRetried is sent when a request of a ticker is retried
+	ProgressRetried
+)
+
+// String returns the name of the kind
+func (k ProgressKind) String() string {
+	switch k {
+	case ProgressStarted:
+		return "started"
+	case ProgressSucceeded:
+		return "succeeded"
+	case ProgressFailed:
+		return "failed"
+	case ProgressRetried:
+		return "retried"
+	default:
+		return "unknown"
+	}
+}
+
+// ProgressEvent reports the progress of a download. The counts include the
+// event itself.
+type ProgressEvent struct {
+	Kind    ProgressKind
+	Ticker  string
+	Err     error         // Why the ticker failed, or why the request is retried
+	Attempt int           // Zero-based attempt that failed, for ProgressRetried
+	Delay   time.Duration // Wait before the retry, for ProgressRetried
+
+	Total     int           // Tickers in the download
+	Started   int           // Tickers started so far
+	Succeeded int           // Tickers downloaded so far
+	Failed    int           // Tickers failed so far
+	Retries   int           // Requests retried so far
+	Elapsed   time.Duration // Time since the download started
+}
+
+// Done returns the number of tickers that succeeded or failed
+func (e ProgressEvent) Done() int {
+	return e.Succeeded + e.Failed
+}
+
+// ETA estimates the time left from the average time per finished ticker,
+// or returns 0 before any ticker is done
+func (e ProgressEvent) ETA() time.Duration {
+	done := e.Done()
+	if done == 0 {
+		return 0
+	}
+	return e.Elapsed / time.Duration(done) * time.Duration(e.Total-done)
+}
+
+// ProgressFunc receives the progress events of a download
+type ProgressFunc func(ProgressEvent)
+
+// progressTracker counts the progress of a download and reports it. Calls
+// to the callbacks are serialized.
+type progressTracker struct {
+	mu        sync.Mutex
+	observers []ProgressFunc
+	onTicker  func(DownloadItem)
+	start     time.Time
+	counts    ProgressEvent
+}
+
+// newProgressTracker returns a tracker for total tickers reporting to the
+// callbacks of options
+func newProgressTracker(total int, options *DownloadOptions) *progressTracker {
+	p := &progressTracker{
+		onTicker: options.OnTicker,
+		start:    time.Now(),
+		counts:   ProgressEvent{Total: total},
+	}
+	if options.OnProgress != nil {
+		p.observers = append(p.observers, options.OnProgress)
+	}
+	if options.Progress {
+		p.observers = append(p.observers, NewProgressBar(progressOutput))
+	}
+	return p
+}
+
+// report updates the counts with event and sends it to the observers
+func (p *progressTracker) report(event ProgressEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch event.Kind {
+	case ProgressStarted:
+		p.counts.Started++
+	case ProgressSucceeded:
+		p.counts.Succeeded++
+	case ProgressFailed:
+		p.counts.Failed++
+	case ProgressRetried:
+		p.counts.Retries++
+	}
+	if len(p.observers) == 0 {
+		return
+	}
+
+	event.Total = p.counts.Total
+	event.Started = p.counts.Started
+	event.Succeeded = p.counts.Succeeded
+	event.Failed = p.counts.Failed
+	event.Retries = p.counts.Retries
+	event.Elapsed = time.Since(p.start)
+	for _, observe := range p.observers {
+		observe(event)
+	}
+}
+
+// finish reports the outcome of a ticker and passes it to OnTicker
+func (p *progressTracker) finish(item DownloadItem) {
+	kind := ProgressSucceeded
+	if item.Err != nil {
+		kind = ProgressFailed
+	}
+	p.report(ProgressEvent{Kind: kind, Ticker: item.Ticker, Err: item.Err})
+
+	if p.onTicker != nil {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.onTicker(item)
+	}
+}
+
+// retryObserver returns a RetryObserver reporting the retries of ticker
+func (p *progressTracker) retryObserver(ticker string) RetryObserver {
+	return func(attempt RetryAttempt, delay time.Duration) {
+		err := attempt.Err
+		if err == nil && attempt.Response != nil {
+			err = fmt.Errorf("HTTP %d", attempt.Response.StatusCode)
+		}
+		p.report(ProgressEvent{
+			Kind:    ProgressRetried,
+			Ticker:  ticker,
+			Err:     err,
+			Attempt: attempt.Attempt,
+			Delay:   delay,
+		})
+	}
+}
+
+// progressOutput is where DownloadOptions.Progress draws its progress bar
+var progressOutput io.Writer = os.Stderr
+
+// progressBarWidth is the number of cells of the progress bar
+const progressBarWidth = 30
+
+// NewProgressBar returns a ProgressFunc drawing a progress bar on w, a
+// terminal. DownloadOptions.Progress draws one on standard error.
+func NewProgressBar(w io.Writer) ProgressFunc {
+	return func(e ProgressEvent) {
+		if (e.Kind != ProgressSucceeded && e.Kind != ProgressFailed) || e.Total == 0 {
+			return
+		}
+
+		done := e.Done()
+		filled := progressBarWidth * done / e.Total
+		bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled)
+		line := fmt.Sprintf("\r[%s] %d/%d %3d%%", bar, done, e.Total, 100*done/e.Total)
+		if e.Failed > 0 {
+			line += fmt.Sprintf(" %d failed", e.Failed)
+		}
+
+		if done == e.Total {
+			fmt.Fprintf(w, "%s in %s\n", line, e.Elapsed.Round(time.Millisecond))
+			return
+		}
+		fmt.Fprintf(w, "%s ETA %s ", line, e.ETA().Round(time.Second))
+	}
+}
@@ -4,65 +4,148 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 )
 
-// Quote represents stock quote information
+// Quote represents stock quote information, as returned by Yahoo's quote
+// endpoint. Fields Yahoo does not report for a symbol keep their zero value.
 type Quote struct {
-	Symbol                   string    `json:"symbol"`
-	ShortName                string    `json:"shortName"`
-	LongName                 string    `json:"longName"`
-	Exchange                 string    `json:"exchange"`
-	Market                   string    `json:"market"`
-	QuoteType                string    `json:"quoteType"`
-	Currency                 string    `json:"currency"`
+	Symbol            string `json:"symbol"`
+	ShortName         string `json:"shortName"`
+	LongName          string `json:"longName"`
+	DisplayName       string `json:"displayName"`
+	Exchange          string `json:"exchange"`         // Exchange code, e.g. NMS
+	FullExchangeName  string `json:"fullExchangeName"` // e.g. NasdaqGS
+	Market            string `json:"market"`
+	QuoteType         string `json:"quoteType"`
+	TypeDisp          string `json:"typeDisp"`
+	Currency          string `json:"currency"`
+	FinancialCurrency string `json:"financialCurrency"`
+	QuoteSourceName   string `json:"quoteSourceName"`
+	Region            string `json:"region"`
+	Language          string `json:"language"`
+	MessageBoardID    string `json:"messageBoardId"`
+
+	// Market status
+	MarketState               string `json:"marketState"` // PRE, REGULAR, POST, CLOSED...
+	ExchangeTimezoneName      string `json:"exchangeTimezoneName"`
+	ExchangeTimezoneShortName string `json:"exchangeTimezoneShortName"`
+	GmtOffsetMilliseconds     int64  `json:"gmtOffSetMilliseconds"`
+	ExchangeDataDelayedBy     int    `json:"exchangeDataDelayedBy"` // Minutes
+	PriceHint                 int    `json:"priceHint"`
+	Tradeable                 bool   `json:"tradeable"`
+	CryptoTradeable           bool   `json:"cryptoTradeable"`
+	Triggerable               bool   `json:"triggerable"`
+	HasPrePostMarketData      bool   `json:"hasPrePostMarketData"`
+	EsgPopulated              bool   `json:"esgPopulated"`
 
 	// Price information
-	RegularMarketPrice       float64   `json:"regularMarketPrice"`
-	RegularMarketChange      float64   `json:"regularMarketChange"`
-	RegularMarketChangePercent float64 `json:"regularMarketChangePercent"`
-	RegularMarketOpen        float64   `json:"regularMarketOpen"`
-	RegularMarketDayHigh     float64   `json:"regularMarketDayHigh"`
-	RegularMarketDayLow      float64   `json:"regularMarketDayLow"`
-	RegularMarketPreviousClose float64 `json:"regularMarketPreviousClose"`
-	RegularMarketVolume      int64     `json:"regularMarketVolume"`
-	RegularMarketTime        time.Time `json:"regularMarketTime"`
+	RegularMarketPrice         float64   `json:"regularMarketPrice"`
+	RegularMarketChange        float64   `json:"regularMarketChange"`
+	RegularMarketChangePercent float64   `json:"regularMarketChangePercent"`
+	RegularMarketOpen          float64   `json:"regularMarketOpen"`
+	RegularMarketDayHigh       float64   `json:"regularMarketDayHigh"`
+	RegularMarketDayLow        float64   `json:"regularMarketDayLow"`
+	RegularMarketDayRange      string    `json:"regularMarketDayRange"`
+	RegularMarketPreviousClose float64   `json:"regularMarketPreviousClose"`
+	RegularMarketVolume        int64     `json:"regularMarketVolume"`
+	RegularMarketTime          time.Time `json:"regularMarketTime"`
+
+	// Order book
+	Bid     float64 `json:"bid"`
+	Ask     float64 `json:"ask"`
+	BidSize int64   `json:"bidSize"` // In lots of 100 shares
+	AskSize int64   `json:"askSize"` // In lots of 100 shares
 
 	// Pre/Post market
-	PreMarketPrice           float64   `json:"preMarketPrice"`
-	PreMarketChange          float64   `json:"preMarketChange"`
-	PreMarketChangePercent   float64   `json:"preMarketChangePercent"`
-	PostMarketPrice          float64   `json:"postMarketPrice"`
-	PostMarketChange         float64   `json:"postMarketChange"`
-	PostMarketChangePercent  float64   `json:"postMarketChangePercent"`
-
-	// 52 week range
-	FiftyTwoWeekLow          float64   `json:"fiftyTwoWeekLow"`
-	FiftyTwoWeekHigh         float64   `json:"fiftyTwoWeekHigh"`
-	FiftyDayAverage          float64   `json:"fiftyDayAverage"`
-	TwoHundredDayAverage     float64   `json:"twoHundredDayAverage"`
+	PreMarketPrice          float64   `json:"preMarketPrice"`
+	PreMarketChange         float64   `json:"preMarketChange"`
+	PreMarketChangePercent  float64   `json:"preMarketChangePercent"`
+	PreMarketTime           time.Time `json:"preMarketTime"`
+	PostMarketPrice         float64   `json:"postMarketPrice"`
+	PostMarketChange        float64   `json:"postMarketChange"`
+	PostMarketChangePercent float64   `json:"postMarketChangePercent"`
+	PostMarketTime          time.Time `json:"postMarketTime"`
+
+	// 52 week range and moving averages
+	FiftyTwoWeekLow                   float64 `json:"fiftyTwoWeekLow"`
+	FiftyTwoWeekHigh                  float64 `json:"fiftyTwoWeekHigh"`
+	FiftyTwoWeekRange                 string  `json:"fiftyTwoWeekRange"`
+	FiftyTwoWeekLowChange             float64 `json:"fiftyTwoWeekLowChange"`
+	FiftyTwoWeekLowChangePercent      float64 `json:"fiftyTwoWeekLowChangePercent"`
+	FiftyTwoWeekHighChange            float64 `json:"fiftyTwoWeekHighChange"`
+	FiftyTwoWeekHighChangePercent     float64 `json:"fiftyTwoWeekHighChangePercent"`
+	FiftyTwoWeekChangePercent         float64 `json:"fiftyTwoWeekChangePercent"`
+	FiftyDayAverage                   float64 `json:"fiftyDayAverage"`
+	FiftyDayAverageChange             float64 `json:"fiftyDayAverageChange"`
+	FiftyDayAverageChangePercent      float64 `json:"fiftyDayAverageChangePercent"`
+	TwoHundredDayAverage              float64 `json:"twoHundredDayAverage"`
+	TwoHundredDayAverageChange        float64 `json:"twoHundredDayAverageChange"`
+	TwoHundredDayAverageChangePercent float64 `json:"twoHundredDayAverageChangePercent"`
+	AverageDailyVolume3Month          int64   `json:"averageDailyVolume3Month"`
+	AverageDailyVolume10Day           int64   `json:"averageDailyVolume10Day"`
 
 	// Market data
-	MarketCap                int64     `json:"marketCap"`
-	SharesOutstanding        int64     `json:"sharesOutstanding"`
-	FloatShares              int64     `json:"floatShares"`
-	Beta                     float64   `json:"beta"`
-	PE                       float64   `json:"trailingPE"`
-	ForwardPE                float64   `json:"forwardPE"`
-	DividendYield            float64   `json:"dividendYield"`
-	EPS                      float64   `json:"trailingEps"`
-	ForwardEPS               float64   `json:"forwardEps"`
+	MarketCap               int64   `json:"marketCap"`
+	SharesOutstanding       int64   `json:"sharesOutstanding"`
+	FloatShares             int64   `json:"floatShares"`
+	Beta                    float64 `json:"beta"`
+	PE                      float64 `json:"trailingPE"`
+	ForwardPE               float64 `json:"forwardPE"`
+	PriceEpsCurrentYear     float64 `json:"priceEpsCurrentYear"`
+	EPS                     float64 `json:"trailingEps"`
+	ForwardEPS              float64 `json:"forwardEps"`
+	EpsTrailingTwelveMonths float64 `json:"epsTrailingTwelveMonths"`
+	EpsCurrentYear          float64 `json:"epsCurrentYear"`
 
 	// Additional info
-	BookValue                float64   `json:"bookValue"`
-	PriceToBook              float64   `json:"priceToBook"`
-	Revenue                  int64     `json:"totalRevenue"`
-	EBITDA                   int64     `json:"ebitda"`
-	ProfitMargin             float64   `json:"profitMargins"`
-	OperatingMargin          float64   `json:"operatingMargins"`
+	BookValue       float64 `json:"bookValue"`
+	PriceToBook     float64 `json:"priceToBook"`
+	Revenue         int64   `json:"totalRevenue"`
+	EBITDA          int64   `json:"ebitda"`
+	ProfitMargin    float64 `json:"profitMargins"`
+	OperatingMargin float64 `json:"operatingMargins"`
+
+	// Dividends and earnings
+	DividendYield               float64   `json:"dividendYield"`               // Forward yield, in percent
+	TrailingAnnualDividendYield float64   `json:"trailingAnnualDividendYield"` // Trailing annual yield, as a fraction
+	TrailingAnnualDividendRate  float64   `json:"trailingAnnualDividendRate"`
+	DividendRate                float64   `json:"dividendRate"` // Forward annual dividend
+	DividendDate                time.Time `json:"dividendDate"`
+	EarningsTimestamp           time.Time `json:"earningsTimestamp"`
+	EarningsTimestampStart      time.Time `json:"earningsTimestampStart"`
+	EarningsTimestampEnd        time.Time `json:"earningsTimestampEnd"`
+
+	// Crypto and FX
+	FromCurrency        string    `json:"fromCurrency"`
+	ToCurrency          string    `json:"toCurrency"`
+	LastMarket          string    `json:"lastMarket"`
+	CirculatingSupply   float64   `json:"circulatingSupply"`
+	Volume24Hr          float64   `json:"volume24Hr"`
+	VolumeAllCurrencies float64   `json:"volumeAllCurrencies"`
+	CoinMarketCapLink   string    `json:"coinMarketCapLink"`
+	CoinImageURL        string    `json:"coinImageUrl"`
+	StartDate           time.Time `json:"startDate"`
+
+	// Funds
+	NetAssets                 float64 `json:"netAssets"`
+	NetExpenseRatio           float64 `json:"netExpenseRatio"`
+	YtdReturn                 float64 `json:"ytdReturn"`
+	TrailingThreeMonthReturns float64 `json:"trailingThreeMonthReturns"`
+
+	// Options and futures
+	UnderlyingSymbol string    `json:"underlyingSymbol"`
+	Strike           float64   `json:"strike"`
+	OpenInterest     int64     `json:"openInterest"`
+	ExpireDate       time.Time `json:"expireDate"`
 
 	// Timestamps
-	FirstTradeDate           time.Time `json:"firstTradeDate"`
+	FirstTradeDate time.Time `json:"firstTradeDate"`
+
+	// Raw holds the fields of the response Quote does not model, as decoded
+	// from JSON
+	Raw map[string]any `json:"raw,omitempty"`
 }
 
 // FastInfo provides quick access to essential ticker information
@@ -91,182 +174,169 @@ type FastInfo struct {
 
 // GetQuote fetches the current quote for the ticker
 func (t *Ticker) GetQuote(ctx context.Context) (*Quote, error) {
-	params := map[string]string{
-		"symbols": t.Symbol,
-		"fields": strings.Join([]string{
-			"symbol",
-			"shortName",
-			"longName",
-			"exchangeName",
-			"market",
-			"quoteType",
-			"currency",
-			"regularMarketPrice",
-			"regularMarketChange",
-			"regularMarketChangePercent",
-			"regularMarketOpen",
-			"regularMarketDayHigh",
-			"regularMarketDayLow",
-			"regularMarketPreviousClose",
-			"regularMarketVolume",
-			"regularMarketTime",
-			"preMarketPrice",
-			"preMarketChange",
-			"preMarketChangePercent",
-			"postMarketPrice",
-			"postMarketChange",
-			"postMarketChangePercent",
-			"fiftyTwoWeekLow",
-			"fiftyTwoWeekHigh",
-			"fiftyDayAverage",
-			"twoHundredDayAverage",
-			"marketCap",
-			"sharesOutstanding",
-			"floatShares",
-			"beta",
-			"trailingPE",
-			"forwardPE",
-			"dividendYield",
-			"trailingEps",
-			"forwardEps",
-			"bookValue",
-			"priceToBook",
-			"totalRevenue",
-			"ebitda",
-			"profitMargins",
-			"operatingMargins",
-		}, ","),
-	}
-
-	endpoint := fmt.Sprintf("%s/v7/finance/quote", Query1URL)
-
-	var result quoteResponse
-	if err := t.data.GetRawJSON(ctx, endpoint, params, &result); err != nil {
+	quotes, err := fetchQuotes(ctx, t.data, []string{t.Symbol}, DefaultQuoteFields)
+	if err != nil {
 		return nil, err
 	}
 
-	if result.QuoteResponse.Error != nil {
-		return nil, fmt.Errorf("quote error: %v", result.QuoteResponse.Error)
-	}
-
-	if len(result.QuoteResponse.Result) == 0 {
+	if len(quotes) == 0 {
 		return nil, NewYFTickerMissingError(t.Symbol, "no quote data found")
 	}
 
-	return parseQuote(result.QuoteResponse.Result[0]), nil
+	return quotes[0], nil
 }
 
 // quoteResponse represents the quote API response
 type quoteResponse struct {
 	QuoteResponse struct {
-		Result []quoteResult `json:"result"`
-		Error  interface{}  `json:"error"`
+		Result []map[string]interface{} `json:"result"`
+		Error  interface{}              `json:"error"`
 	} `json:"quoteResponse"`
 }
 
-// quoteResult represents a single quote result
-type quoteResult struct {
-	Symbol                     string      `json:"symbol"`
-	ShortName                  string      `json:"shortName"`
-	LongName                   string      `json:"longName"`
-	ExchangeName               string      `json:"exchangeName"`
-	Market                     string      `json:"market"`
-	QuoteType                  string      `json:"quoteType"`
-	Currency                   string      `json:"currency"`
-	RegularMarketPrice         float64     `json:"regularMarketPrice"`
-	RegularMarketChange        float64     `json:"regularMarketChange"`
-	RegularMarketChangePercent float64     `json:"regularMarketChangePercent"`
-	RegularMarketOpen          float64     `json:"regularMarketOpen"`
-	RegularMarketDayHigh       float64     `json:"regularMarketDayHigh"`
-	RegularMarketDayLow        float64     `json:"regularMarketDayLow"`
-	RegularMarketPreviousClose float64     `json:"regularMarketPreviousClose"`
-	RegularMarketVolume        int64       `json:"regularMarketVolume"`
-	RegularMarketTime          interface{} `json:"regularMarketTime"` // Can be int or int64
-	PreMarketPrice             float64     `json:"preMarketPrice"`
-	PreMarketChange            float64     `json:"preMarketChange"`
-	PreMarketChangePercent     float64     `json:"preMarketChangePercent"`
-	PostMarketPrice            float64     `json:"postMarketPrice"`
-	PostMarketChange           float64     `json:"postMarketChange"`
-	PostMarketChangePercent    float64     `json:"postMarketChangePercent"`
-	FiftyTwoWeekLow            float64     `json:"fiftyTwoWeekLow"`
-	FiftyTwoWeekHigh           float64     `json:"fiftyTwoWeekHigh"`
-	FiftyDayAverage            float64     `json:"fiftyDayAverage"`
-	TwoHundredDayAverage       float64     `json:"twoHundredDayAverage"`
-	MarketCap                  int64       `json:"marketCap"`
-	SharesOutstanding          int64       `json:"sharesOutstanding"`
-	FloatShares                int64       `json:"floatShares"`
-	Beta                       float64     `json:"beta"`
-	TrailingPE                 float64     `json:"trailingPE"`
-	ForwardPE                  float64     `json:"forwardPE"`
-	TrailingAnnualDividendYield float64    `json:"trailingAnnualDividendYield"`
-	TrailingEps                float64     `json:"trailingEps"`
-	ForwardEps                 float64     `json:"forwardEps"`
-	BookValue                  float64     `json:"bookValue"`
-	PriceToBook                float64     `json:"priceToBook"`
-	TotalRevenue               int64       `json:"totalRevenue"`
-	EBITDA                     int64       `json:"ebitda"`
-	ProfitMargins              float64     `json:"profitMargins"`
-	OperatingMargins           float64     `json:"operatingMargins"`
-	FirstTradeDateMilliseconds int64       `json:"firstTradeDateMilliseconds"`
-}
+// modeledQuoteFields are the response fields parseQuote maps onto Quote
+var modeledQuoteFields = func() map[string]bool {
+	fields := make(map[string]bool, len(DefaultQuoteFields))
+	for _, field := range DefaultQuoteFields {
+		fields[field] = true
+	}
+	return fields
+}()
 
-// parseQuote converts quoteResult to Quote
-func parseQuote(qr quoteResult) *Quote {
+// parseQuote converts a quote result to Quote
+func parseQuote(m map[string]interface{}) *Quote {
 	quote := &Quote{
-		Symbol:                     qr.Symbol,
-		ShortName:                  qr.ShortName,
-		LongName:                   qr.LongName,
-		Exchange:                   qr.ExchangeName,
-		Market:                     qr.Market,
-		QuoteType:                  qr.QuoteType,
-		Currency:                   qr.Currency,
-		RegularMarketPrice:         qr.RegularMarketPrice,
-		RegularMarketChange:        qr.RegularMarketChange,
-		RegularMarketChangePercent: qr.RegularMarketChangePercent,
-		RegularMarketOpen:          qr.RegularMarketOpen,
-		RegularMarketDayHigh:       qr.RegularMarketDayHigh,
-		RegularMarketDayLow:        qr.RegularMarketDayLow,
-		RegularMarketPreviousClose: qr.RegularMarketPreviousClose,
-		RegularMarketVolume:        qr.RegularMarketVolume,
-		PreMarketPrice:             qr.PreMarketPrice,
-		PreMarketChange:            qr.PreMarketChange,
-		PreMarketChangePercent:     qr.PreMarketChangePercent,
-		PostMarketPrice:            qr.PostMarketPrice,
-		PostMarketChange:           qr.PostMarketChange,
-		PostMarketChangePercent:    qr.PostMarketChangePercent,
-		FiftyTwoWeekLow:            qr.FiftyTwoWeekLow,
-		FiftyTwoWeekHigh:           qr.FiftyTwoWeekHigh,
-		FiftyDayAverage:            qr.FiftyDayAverage,
-		TwoHundredDayAverage:       qr.TwoHundredDayAverage,
-		MarketCap:                  qr.MarketCap,
-		SharesOutstanding:          qr.SharesOutstanding,
-		FloatShares:                qr.FloatShares,
-		Beta:                       qr.Beta,
-		PE:                         qr.TrailingPE,
-		ForwardPE:                  qr.ForwardPE,
-		DividendYield:              qr.TrailingAnnualDividendYield,
-		EPS:                        qr.TrailingEps,
-		ForwardEPS:                 qr.ForwardEps,
-		BookValue:                  qr.BookValue,
-		PriceToBook:                qr.PriceToBook,
-		Revenue:                    qr.TotalRevenue,
-		EBITDA:                     qr.EBITDA,
-		ProfitMargin:               qr.ProfitMargins,
-		OperatingMargin:            qr.OperatingMargins,
+		Symbol:            getString(m, "symbol"),
+		ShortName:         getString(m, "shortName"),
+		LongName:          getString(m, "longName"),
+		DisplayName:       getString(m, "displayName"),
+		Exchange:          getString(m, "exchange"),
+		FullExchangeName:  getString(m, "fullExchangeName"),
+		Market:            getString(m, "market"),
+		QuoteType:         getString(m, "quoteType"),
+		TypeDisp:          getString(m, "typeDisp"),
+		Currency:          getString(m, "currency"),
+		FinancialCurrency: getString(m, "financialCurrency"),
+		QuoteSourceName:   getString(m, "quoteSourceName"),
+		Region:            getString(m, "region"),
+		Language:          getString(m, "language"),
+		MessageBoardID:    getString(m, "messageBoardId"),
+
+		MarketState:               getString(m, "marketState"),
+		ExchangeTimezoneName:      getString(m, "exchangeTimezoneName"),
+		ExchangeTimezoneShortName: getString(m, "exchangeTimezoneShortName"),
+		GmtOffsetMilliseconds:     getInt64(m, "gmtOffSetMilliseconds"),
+		ExchangeDataDelayedBy:     getInt(m, "exchangeDataDelayedBy"),
+		PriceHint:                 getInt(m, "priceHint"),
+		Tradeable:                 getBool(m, "tradeable"),
+		CryptoTradeable:           getBool(m, "cryptoTradeable"),
+		Triggerable:               getBool(m, "triggerable"),
+		HasPrePostMarketData:      getBool(m, "hasPrePostMarketData"),
+		EsgPopulated:              getBool(m, "esgPopulated"),
+
+		RegularMarketPrice:         getFloat64(m, "regularMarketPrice"),
+		RegularMarketChange:        getFloat64(m, "regularMarketChange"),
+		RegularMarketChangePercent: getFloat64(m, "regularMarketChangePercent"),
+		RegularMarketOpen:          getFloat64(m, "regularMarketOpen"),
+		RegularMarketDayHigh:       getFloat64(m, "regularMarketDayHigh"),
+		RegularMarketDayLow:        getFloat64(m, "regularMarketDayLow"),
+		RegularMarketDayRange:      getString(m, "regularMarketDayRange"),
+		RegularMarketPreviousClose: getFloat64(m, "regularMarketPreviousClose"),
+		RegularMarketVolume:        getInt64(m, "regularMarketVolume"),
+		RegularMarketTime:          getTime(m, "regularMarketTime"),
+
+		Bid:     getFloat64(m, "bid"),
+		Ask:     getFloat64(m, "ask"),
+		BidSize: getInt64(m, "bidSize"),
+		AskSize: getInt64(m, "askSize"),
+
+		PreMarketPrice:          getFloat64(m, "preMarketPrice"),
+		PreMarketChange:         getFloat64(m, "preMarketChange"),
+		PreMarketChangePercent:  getFloat64(m, "preMarketChangePercent"),
+		PreMarketTime:           getTime(m, "preMarketTime"),
+		PostMarketPrice:         getFloat64(m, "postMarketPrice"),
+		PostMarketChange:        getFloat64(m, "postMarketChange"),
+		PostMarketChangePercent: getFloat64(m, "postMarketChangePercent"),
+		PostMarketTime:          getTime(m, "postMarketTime"),
+
+		FiftyTwoWeekLow:                   getFloat64(m, "fiftyTwoWeekLow"),
+		FiftyTwoWeekHigh:                  getFloat64(m, "fiftyTwoWeekHigh"),
+		FiftyTwoWeekRange:                 getString(m, "fiftyTwoWeekRange"),
+		FiftyTwoWeekLowChange:             getFloat64(m, "fiftyTwoWeekLowChange"),
+		FiftyTwoWeekLowChangePercent:      getFloat64(m, "fiftyTwoWeekLowChangePercent"),
+		FiftyTwoWeekHighChange:            getFloat64(m, "fiftyTwoWeekHighChange"),
+		FiftyTwoWeekHighChangePercent:     getFloat64(m, "fiftyTwoWeekHighChangePercent"),
+		FiftyTwoWeekChangePercent:         getFloat64(m, "fiftyTwoWeekChangePercent"),
+		FiftyDayAverage:                   getFloat64(m, "fiftyDayAverage"),
+		FiftyDayAverageChange:             getFloat64(m, "fiftyDayAverageChange"),
+		FiftyDayAverageChangePercent:      getFloat64(m, "fiftyDayAverageChangePercent"),
+		TwoHundredDayAverage:              getFloat64(m, "twoHundredDayAverage"),
+		TwoHundredDayAverageChange:        getFloat64(m, "twoHundredDayAverageChange"),
+		TwoHundredDayAverageChangePercent: getFloat64(m, "twoHundredDayAverageChangePercent"),
+		AverageDailyVolume3Month:          getInt64(m, "averageDailyVolume3Month"),
+		AverageDailyVolume10Day:           getInt64(m, "averageDailyVolume10Day"),
+
+		MarketCap:               getInt64(m, "marketCap"),
+		SharesOutstanding:       getInt64(m, "sharesOutstanding"),
+		FloatShares:             getInt64(m, "floatShares"),
+		Beta:                    getFloat64(m, "beta"),
+		PE:                      getFloat64(m, "trailingPE"),
+		ForwardPE:               getFloat64(m, "forwardPE"),
+		PriceEpsCurrentYear:     getFloat64(m, "priceEpsCurrentYear"),
+		EPS:                     getFloat64(m, "trailingEps"),
+		ForwardEPS:              getFloat64(m, "forwardEps"),
+		EpsTrailingTwelveMonths: getFloat64(m, "epsTrailingTwelveMonths"),
+		EpsCurrentYear:          getFloat64(m, "epsCurrentYear"),
+
+		BookValue:       getFloat64(m, "bookValue"),
+		PriceToBook:     getFloat64(m, "priceToBook"),
+		Revenue:         getInt64(m, "totalRevenue"),
+		EBITDA:          getInt64(m, "ebitda"),
+		ProfitMargin:    getFloat64(m, "profitMargins"),
+		OperatingMargin: getFloat64(m, "operatingMargins"),
+
+		DividendYield:               getFloat64(m, "dividendYield"),
+		TrailingAnnualDividendYield: getFloat64(m, "trailingAnnualDividendYield"),
+		TrailingAnnualDividendRate:  getFloat64(m, "trailingAnnualDividendRate"),
+		DividendRate:                getFloat64(m, "dividendRate"),
+		DividendDate:                getTime(m, "dividendDate"),
+		EarningsTimestamp:           getTime(m, "earningsTimestamp"),
+		EarningsTimestampStart:      getTime(m, "earningsTimestampStart"),
+		EarningsTimestampEnd:        getTime(m, "earningsTimestampEnd"),
+
+		FromCurrency:        getString(m, "fromCurrency"),
+		ToCurrency:          getString(m, "toCurrency"),
+		LastMarket:          getString(m, "lastMarket"),
+		CirculatingSupply:   getFloat64(m, "circulatingSupply"),
+		Volume24Hr:          getFloat64(m, "volume24Hr"),
+		VolumeAllCurrencies: getFloat64(m, "volumeAllCurrencies"),
+		CoinMarketCapLink:   getString(m, "coinMarketCapLink"),
+		CoinImageURL:        getString(m, "coinImageUrl"),
+		StartDate:           getTime(m, "startDate"),
+
+		NetAssets:                 getFloat64(m, "netAssets"),
+		NetExpenseRatio:           getFloat64(m, "netExpenseRatio"),
+		YtdReturn:                 getFloat64(m, "ytdReturn"),
+		TrailingThreeMonthReturns: getFloat64(m, "trailingThreeMonthReturns"),
+
+		UnderlyingSymbol: getString(m, "underlyingSymbol"),
+		Strike:           getFloat64(m, "strike"),
+		OpenInterest:     getInt64(m, "openInterest"),
+		ExpireDate:       getTime(m, "expireDate"),
 	}
 
-	// Parse timestamps
-	switch v := qr.RegularMarketTime.(type) {
-	case int64:
-		quote.RegularMarketTime = time.Unix(v, 0)
-	case int:
-		quote.RegularMarketTime = time.Unix(int64(v), 0)
-	case float64:
-		quote.RegularMarketTime = time.Unix(int64(v), 0)
+	if ms := getInt64(m, "firstTradeDateMilliseconds"); ms > 0 {
+		quote.FirstTradeDate = time.UnixMilli(ms)
 	}
 
-	if qr.FirstTradeDateMilliseconds > 0 {
-		quote.FirstTradeDate = time.Unix(qr.FirstTradeDateMilliseconds/1000, 0)
+	for key, value := range m {
+		if modeledQuoteFields[key] {
+			continue
+		}
+		if quote.Raw == nil {
+			quote.Raw = make(map[string]any)
+		}
+		quote.Raw[key] = value
 	}
 
 	return quote
@@ -310,9 +380,15 @@ func (t *Ticker) GetFastInfo(ctx context.Context) (*FastInfo, error) {
 
 	// Calculate year change
 	var yearChange float64
-	if len(history.Data) > 0 {
-		firstPrice := history.Data[0].Close
-		lastPrice := history.Data[len(history.Data)-1].Close
+	var closes []float64
+	for _, bar := range history.Data {
+		if !bar.IsMissing(FieldClose) {
+			closes = append(closes, bar.Close)
+		}
+	}
+	if len(closes) > 0 {
+		firstPrice := closes[0]
+		lastPrice := closes[len(closes)-1]
 		if firstPrice > 0 {
 			yearChange = (lastPrice - firstPrice) / firstPrice * 100
 		}
@@ -342,40 +418,253 @@ func (t *Ticker) GetFastInfo(ctx context.Context) (*FastInfo, error) {
 	}, nil
 }
 
-// GetQuotes fetches quotes for multiple tickers
+// QuoteBatchSize is the default number of symbols requested per quote call
+const QuoteBatchSize = 100
+
+// DefaultQuoteFields are the fields GetQuote and GetQuotes request, unless
+// QuotesOptions.Fields says otherwise. They are the fields Quote models;
+// any other field Yahoo returns lands in Quote.Raw.
+var DefaultQuoteFields = []string{
+	"symbol",
+	"shortName",
+	"longName",
+	"displayName",
+	"exchange",
+	"fullExchangeName",
+	"market",
+	"quoteType",
+	"typeDisp",
+	"currency",
+	"financialCurrency",
+	"quoteSourceName",
+	"region",
+	"language",
+	"messageBoardId",
+	"marketState",
+	"exchangeTimezoneName",
+	"exchangeTimezoneShortName",
+	"gmtOffSetMilliseconds",
+	"exchangeDataDelayedBy",
+	"priceHint",
+	"tradeable",
+	"cryptoTradeable",
+	"triggerable",
+	"hasPrePostMarketData",
+	"esgPopulated",
+	"regularMarketPrice",
+	"regularMarketChange",
+	"regularMarketChangePercent",
+	"regularMarketOpen",
+	"regularMarketDayHigh",
+	"regularMarketDayLow",
+	"regularMarketDayRange",
+	"regularMarketPreviousClose",
+	"regularMarketVolume",
+	"regularMarketTime",
+	"bid",
+	"ask",
+	"bidSize",
+	"askSize",
+	"preMarketPrice",
+	"preMarketChange",
+	"preMarketChangePercent",
+	"preMarketTime",
+	"postMarketPrice",
+	"postMarketChange",
+	"postMarketChangePercent",
+	"postMarketTime",
+	"fiftyTwoWeekLow",
+	"fiftyTwoWeekHigh",
+	"fiftyTwoWeekRange",
+	"fiftyTwoWeekLowChange",
+	"fiftyTwoWeekLowChangePercent",
+	"fiftyTwoWeekHighChange",
+	"fiftyTwoWeekHighChangePercent",
+	"fiftyTwoWeekChangePercent",
+	"fiftyDayAverage",
+	"fiftyDayAverageChange",
+	"fiftyDayAverageChangePercent",
+	"twoHundredDayAverage",
+	"twoHundredDayAverageChange",
+	"twoHundredDayAverageChangePercent",
+	"averageDailyVolume3Month",
+	"averageDailyVolume10Day",
+	"marketCap",
+	"sharesOutstanding",
+	"floatShares",
+	"beta",
+	"trailingPE",
+	"forwardPE",
+	"priceEpsCurrentYear",
+	"trailingEps",
+	"forwardEps",
+	"epsTrailingTwelveMonths",
+	"epsCurrentYear",
+	"bookValue",
+	"priceToBook",
+	"totalRevenue",
+	"ebitda",
+	"profitMargins",
+	"operatingMargins",
+	"dividendYield",
+	"trailingAnnualDividendYield",
+	"trailingAnnualDividendRate",
+	"dividendRate",
+	"dividendDate",
+	"earningsTimestamp",
+	"earningsTimestampStart",
+	"earningsTimestampEnd",
+	"fromCurrency",
+	"toCurrency",
+	"lastMarket",
+	"circulatingSupply",
+	"volume24Hr",
+	"volumeAllCurrencies",
+	"coinMarketCapLink",
+	"coinImageUrl",
+	"startDate",
+	"netAssets",
+	"netExpenseRatio",
+	"ytdReturn",
+	"trailingThreeMonthReturns",
+	"underlyingSymbol",
+	"strike",
+	"openInterest",
+	"expireDate",
+	"firstTradeDateMilliseconds",
+}
+
+// QuotesOptions configures GetQuotesWithOptions
+type QuotesOptions struct {
+	Fields    []string // Fields to request (DefaultQuoteFields if empty)
+	BatchSize int      // Symbols per request (QuoteBatchSize if 0)
+	Threads   int      // Batches fetched in parallel (4 if 0)
+}
+
+// QuotesResult holds the quotes of GetQuotesWithOptions
+type QuotesResult struct {
+	Quotes  map[string]*Quote // Quotes keyed by symbol
+	Missing []string          // Requested symbols Yahoo returned no quote for, in batches that succeeded
+}
+
+// GetQuotes fetches quotes for multiple tickers, in the order requested.
+// Symbols Yahoo does not know are left out. Calls share one session while
+// GlobalConfig is unchanged.
 func GetQuotes(ctx context.Context, symbols []string) ([]*Quote, error) {
-	if len(symbols) == 0 {
-		return []*Quote{}, nil
+	return getQuotes(ctx, sharedYfData(), symbols)
+}
+
+// GetQuotesWithOptions fetches quotes for any number of tickers, in
+// parallel batches. If a batch fails, its error is returned along with the
+// result of the other batches; its symbols are not reported as Missing.
+func GetQuotesWithOptions(ctx context.Context, symbols []string, options *QuotesOptions) (*QuotesResult, error) {
+	return getQuotesWithOptions(ctx, sharedYfData(), symbols, options)
+}
+
+// getQuotes fetches quotes for multiple tickers using the given session
+func getQuotes(ctx context.Context, data *YfData, symbols []string) ([]*Quote, error) {
+	result, err := getQuotesWithOptions(ctx, data, symbols, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	quotes := make([]*Quote, 0, len(result.Quotes))
+	for _, symbol := range normalizeSymbols(symbols) {
+		if quote, ok := result.Quotes[symbol]; ok {
+			quotes = append(quotes, quote)
+		}
+	}
+	return quotes, nil
+}
+
+// getQuotesWithOptions fetches quotes in batches using the given session.
+// On error, the quotes and missing symbols of the batches that succeeded
+// are still returned; symbols of failed batches are in neither.
+func getQuotesWithOptions(ctx context.Context, data *YfData, symbols []string, options *QuotesOptions) (*QuotesResult, error) {
+	if options == nil {
+		options = &QuotesOptions{}
+	}
+	fields := options.Fields
+	if len(fields) == 0 {
+		fields = DefaultQuoteFields
+	}
+	batchSize := options.BatchSize
+	if batchSize <= 0 {
+		batchSize = QuoteBatchSize
+	}
+	threads := options.Threads
+	if threads <= 0 {
+		threads = 4
+	}
+
+	symbols = normalizeSymbols(symbols)
+	var batches [][]string
+	for start := 0; start < len(symbols); start += batchSize {
+		batches = append(batches, symbols[start:min(start+batchSize, len(symbols))])
+	}
+
+	result := &QuotesResult{Quotes: make(map[string]*Quote, len(symbols))}
+	var mu sync.Mutex
+	var firstErr error
+	fetched := make(map[string]bool, len(symbols))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, threads)
+queue:
+	for _, batch := range batches {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			mu.Lock()
+			if firstErr == nil {
+				firstErr = ctx.Err()
+			}
+			mu.Unlock()
+			break queue
+		}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			quotes, err := fetchQuotes(ctx, data, batch, fields)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			for _, symbol := range batch {
+				fetched[symbol] = true
+			}
+			for _, quote := range quotes {
+				result.Quotes[strings.ToUpper(quote.Symbol)] = quote
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, symbol := range symbols {
+		if _, ok := result.Quotes[symbol]; !ok && fetched[symbol] {
+			result.Missing = append(result.Missing, symbol)
+		}
 	}
 
+	return result, firstErr
+}
+
+// fetchQuotes fetches the quotes of one batch of symbols
+func fetchQuotes(ctx context.Context, data *YfData, symbols, fields []string) ([]*Quote, error) {
 	params := map[string]string{
 		"symbols": strings.Join(symbols, ","),
-		"fields": strings.Join([]string{
-			"symbol",
-			"shortName",
-			"longName",
-			"exchangeName",
-			"market",
-			"quoteType",
-			"currency",
-			"regularMarketPrice",
-			"regularMarketChange",
-			"regularMarketChangePercent",
-			"regularMarketOpen",
-			"regularMarketDayHigh",
-			"regularMarketDayLow",
-			"regularMarketPreviousClose",
-			"regularMarketVolume",
-			"regularMarketTime",
-			"fiftyTwoWeekLow",
-			"fiftyTwoWeekHigh",
-			"marketCap",
-			"sharesOutstanding",
-		}, ","),
+		"fields":  strings.Join(fields, ","),
 	}
 
-	endpoint := fmt.Sprintf("%s/v7/finance/quote", Query1URL)
-	data := NewYfData()
+	endpoint := fmt.Sprintf("%s/v7/finance/quote", data.endpoints.Query1)
 
 	var result quoteResponse
 	if err := data.GetRawJSON(ctx, endpoint, params, &result); err != nil {
@@ -393,3 +682,18 @@ func GetQuotes(ctx context.Context, symbols []string) ([]*Quote, error) {
 
 	return quotes, nil
 }
+
+// normalizeSymbols upper-cases and trims symbols, dropping empty and
+// duplicate ones
+func normalizeSymbols(symbols []string) []string {
+	normalized := make([]string, 0, len(symbols))
+	seen := make(map[string]bool)
+	for _, s := range symbols {
+		s = strings.ToUpper(strings.TrimSpace(s))
+		if s != "" && !seen[s] {
+			normalized = append(normalized, s)
+			seen[s] = true
+		}
+	}
+	return normalized
+}
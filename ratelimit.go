@@ -0,0 +1,154 @@
+package yfinance
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RateLimit describes a token bucket: Rate requests per second on average,
+// with up to Burst requests allowed at once
+type RateLimit struct {
+	Rate  float64 // Steady-state requests per second (0 = unlimited)
+	Burst int     // Maximum number of requests sent back to back
+}
+
+// DefaultRateLimits returns per-host limits that keep large batch jobs
+// below Yahoo's throttling thresholds
+func DefaultRateLimits() map[string]RateLimit {
+	return map[string]RateLimit{
+		"query1.finance.yahoo.com": {Rate: 5, Burst: 10},
+		"query2.finance.yahoo.com": {Rate: 5, Burst: 10},
+		"finance.yahoo.com":        {Rate: 1, Burst: 2},
+	}
+}
+
+// RateLimiter throttles requests with one token bucket per host.
+//
+// A RateLimiter is safe for concurrent use and can be shared by several
+// clients so that they draw from the same budget.
+type RateLimiter struct {
+	mu      sync.Mutex
+	def     RateLimit
+	hosts   map[string]RateLimit
+	buckets map[string]*tokenBucket
+}
+
+// NewRateLimiter creates a RateLimiter. Hosts listed in perHost use their
+// own limit, all other hosts use def.
+func NewRateLimiter(def RateLimit, perHost map[string]RateLimit) *RateLimiter {
+	hosts := make(map[string]RateLimit, len(perHost))
+	for host, limit := range perHost {
+		hosts[host] = limit
+	}
+	return &RateLimiter{
+		def:     def,
+		hosts:   hosts,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// Wait blocks until a request to host may be sent or ctx is done
+func (l *RateLimiter) Wait(ctx context.Context, host string) error {
+	b := l.bucket(host)
+	if b == nil {
+		return ctx.Err()
+	}
+
+	delay := b.reserve(time.Now())
+	if err := sleepContext(ctx, delay); err != nil {
+		b.cancel()
+		return err
+	}
+	return nil
+}
+
+// Middleware returns a Middleware that waits for the limiter before every
+// request
+func (l *RateLimiter) Middleware() Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			if err := l.Wait(req.Context(), req.URL.Hostname()); err != nil {
+				return nil, err
+			}
+			return next.Do(req)
+		})
+	}
+}
+
+// bucket returns the token bucket for host, or nil if host is unlimited
+func (l *RateLimiter) bucket(host string) *tokenBucket {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if b, ok := l.buckets[host]; ok {
+		return b
+	}
+
+	limit, ok := l.hosts[host]
+	if !ok {
+		limit = l.def
+	}
+
+	var b *tokenBucket
+	if limit.Rate > 0 {
+		b = newTokenBucket(limit)
+	}
+	l.buckets[host] = b
+	return b
+}
+
+// tokenBucket is a token bucket that hands out reservations, so waiters
+// are served in the order they arrived
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket creates a full token bucket
+func newTokenBucket(limit RateLimit) *tokenBucket {
+	burst := float64(limit.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   limit.Rate,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// reserve takes a token and returns how long to wait before using it
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+	}
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// cancel returns a token whose reservation was not used
+func (b *tokenBucket) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens++
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
@@ -0,0 +1,374 @@
+package yfinance
+
+import (
+	"context"
+	"math"
+	"strings"
+	"time"
+)
+
+// RepairReason records why a bar was changed by HistoryOptions.Repair
+type RepairReason uint8
+
+const (
+	// RepairUnitMixup marks prices that were 100x off, e.g. GBp reported as GBP
+	RepairUnitMixup RepairReason = 1 << iota
+	// RepairSplit marks bars Yahoo did not adjust for a split
+	RepairSplit
+	// RepairMissing marks zero or missing prices rebuilt from finer data
+	RepairMissing
+	// RepairVolume marks a zero or missing volume rebuilt from finer data
+	RepairVolume
+)
+
+// String returns the names of the reasons, separated by "|"
+func (r RepairReason) String() string {
+	if r == 0 {
+		return "none"
+	}
+	var names []string
+	for _, reason := range []struct {
+		flag RepairReason
+		name string
+	}{
+		{RepairUnitMixup, "unit-mixup"},
+		{RepairSplit, "split"},
+		{RepairMissing, "missing"},
+		{RepairVolume, "volume"},
+	} {
+		if r&reason.flag != 0 {
+			names = append(names, reason.name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// repairTolerance is how far, as a factor, a price ratio may be from the
+// expected one (100 for unit mixups, the split ratio for splits)
+const repairTolerance = 1.4
+
+// finerIntervals maps an interval to the next finer one, used to rebuild
+// bad bars
+var finerIntervals = map[string]string{
+	"3mo": "1d",
+	"1mo": "1d",
+	"1wk": "1d",
+	"5d":  "1d",
+	"1d":  "1h",
+	"90m": "30m",
+	"60m": "30m",
+	"1h":  "30m",
+	"30m": "15m",
+	"15m": "5m",
+	"5m":  "2m",
+	"2m":  "1m",
+}
+
+// intervalDurations is the nominal length of a bar of each interval
+var intervalDurations = map[string]time.Duration{
+	"1m":  time.Minute,
+	"2m":  2 * time.Minute,
+	"5m":  5 * time.Minute,
+	"15m": 15 * time.Minute,
+	"30m": 30 * time.Minute,
+	"60m": time.Hour,
+	"90m": 90 * time.Minute,
+	"1h":  time.Hour,
+	"1d":  24 * time.Hour,
+	"5d":  5 * 24 * time.Hour,
+	"1wk": 7 * 24 * time.Hour,
+	"1mo": 31 * 24 * time.Hour,
+	"3mo": 92 * 24 * time.Hour,
+}
+
+// repair detects and fixes price anomalies in hr, in the order Python
+// yfinance applies them: unit mixups, missed split adjustments, then bad
+// bars rebuilt from finer data
+func (t *Ticker) repair(ctx context.Context, hr *HistoryResult, options *HistoryOptions) {
+	if len(hr.Data) == 0 {
+		return
+	}
+	repairUnitMixups(hr.Data)
+	repairMissedSplits(hr.Data, hr.Splits)
+	t.repairBadBars(ctx, hr.Data, options)
+}
+
+// near reports whether ratio is within repairTolerance of target
+func near(ratio, target float64) bool {
+	if !(ratio > 0) || !(target > 0) {
+		return false
+	}
+	return math.Abs(math.Log(ratio/target)) < math.Log(repairTolerance)
+}
+
+// repairUnitMixups fixes prices reported 100x too high or too low.
+//
+// The latest bar is assumed to use the right unit. Walking backwards, each
+// close is compared with the corrected close after it, so both isolated
+// bars and whole stretches in the wrong unit are fixed. Open, high and low
+// are then checked against the corrected close of their own bar.
+func repairUnitMixups(data []PriceData) {
+	next := math.NaN()
+	for i := len(data) - 1; i >= 0; i-- {
+		bar := &data[i]
+		if bar.IsMissing(FieldClose) || bar.Close <= 0 {
+			continue
+		}
+
+		scale := 1.0
+		if !math.IsNaN(next) {
+			switch ratio := bar.Close / next; {
+			case near(ratio, 100):
+				scale = 0.01
+			case near(ratio, 0.01):
+				scale = 100
+			}
+		}
+		if scale != 1 {
+			bar.Close *= scale
+			bar.AdjClose *= scale
+			bar.Repaired |= RepairUnitMixup
+		}
+		next = bar.Close
+
+		for _, v := range []*float64{&bar.Open, &bar.High, &bar.Low, &bar.AdjClose} {
+			switch ratio := *v / bar.Close; {
+			case near(ratio, 100):
+				*v /= 100
+				bar.Repaired |= RepairUnitMixup
+			case near(ratio, 0.01):
+				*v *= 100
+				bar.Repaired |= RepairUnitMixup
+			}
+		}
+	}
+}
+
+// repairMissedSplits adjusts bars before a split when the prices jump by
+// the split ratio across the split date, meaning Yahoo did not apply it
+func repairMissedSplits(data []PriceData, splits []SplitData) {
+	for _, split := range splits {
+		if split.Numerator <= 0 || split.Denominator <= 0 {
+			continue
+		}
+		ratio := split.Numerator / split.Denominator
+		if near(ratio, 1) {
+			continue
+		}
+
+		at := -1
+		for i, bar := range data {
+			if !bar.Date.Before(split.Date) {
+				at = i
+				break
+			}
+		}
+		if at <= 0 {
+			continue
+		}
+
+		before, after := lastClose(data[:at]), firstClose(data[at:])
+		if !near(before/after, ratio) {
+			continue
+		}
+
+		for i := 0; i < at; i++ {
+			bar := &data[i]
+			bar.Open /= ratio
+			bar.High /= ratio
+			bar.Low /= ratio
+			bar.Close /= ratio
+			bar.AdjClose /= ratio
+			if !bar.IsMissing(FieldVolume) {
+				bar.Volume = int64(math.Round(float64(bar.Volume) * ratio))
+			}
+			bar.Repaired |= RepairSplit
+		}
+	}
+}
+
+// firstClose returns the first reported close in data, or NaN
+func firstClose(data []PriceData) float64 {
+	for _, bar := range data {
+		if !bar.IsMissing(FieldClose) && bar.Close > 0 {
+			return bar.Close
+		}
+	}
+	return math.NaN()
+}
+
+// lastClose returns the last reported close in data, or NaN
+func lastClose(data []PriceData) float64 {
+	for i := len(data) - 1; i >= 0; i-- {
+		if !data[i].IsMissing(FieldClose) && data[i].Close > 0 {
+			return data[i].Close
+		}
+	}
+	return math.NaN()
+}
+
+// badPrice reports whether a price is missing or zero
+func badPrice(v float64) bool {
+	return math.IsNaN(v) || v <= 0
+}
+
+// isBadBar reports whether a bar has a zero or missing price, or no volume
+// although the price moved
+func isBadBar(bar PriceData) bool {
+	if bar.IsEmpty() {
+		return false
+	}
+	if badPrice(bar.Open) || badPrice(bar.High) || badPrice(bar.Low) || badPrice(bar.Close) {
+		return true
+	}
+	return isBadVolume(bar)
+}
+
+// isBadVolume reports whether a bar has no volume although the price moved
+func isBadVolume(bar PriceData) bool {
+	return (bar.IsMissing(FieldVolume) || bar.Volume == 0) && bar.High != bar.Low
+}
+
+// repairBadBars rebuilds bars with zero or missing values from finer
+// interval data. Bars older than the lookback of the finer interval, or
+// without finer data, are left untouched.
+func (t *Ticker) repairBadBars(ctx context.Context, data []PriceData, options *HistoryOptions) {
+	finer, ok := finerIntervals[options.Interval]
+	if !ok {
+		return
+	}
+
+	var bad []int
+	var earliest time.Time
+	if limit, ok := IntervalLimits[finer]; ok {
+		// Leave a margin so that the fetch stays within the lookback
+		earliest = time.Now().Add(-limit.MaxLookback + time.Hour)
+	}
+	for i, bar := range data {
+		if isBadBar(bar) && !bar.Date.Before(earliest) {
+			bad = append(bad, i)
+		}
+	}
+	if len(bad) == 0 {
+		return
+	}
+
+	barEnd := func(i int) time.Time {
+		if i+1 < len(data) {
+			return data[i+1].Date
+		}
+		return data[i].Date.Add(intervalDurations[options.Interval])
+	}
+
+	start := data[bad[0]].Date
+	end := barEnd(bad[len(bad)-1])
+	fine, err := t.History(ctx, &HistoryOptions{
+		Interval: finer,
+		Start:    &start,
+		End:      &end,
+		PrePost:  options.PrePost,
+	})
+	if err != nil || len(fine.Data) == 0 {
+		return
+	}
+
+	for _, i := range bad {
+		agg, ok := aggregate(fine.Data, data[i].Date, barEnd(i))
+		if !ok {
+			continue
+		}
+		rebuildBar(data, i, agg)
+	}
+}
+
+// aggregate combines the bars in [start, end) into one bar
+func aggregate(data []PriceData, start, end time.Time) (PriceData, bool) {
+	agg := PriceData{
+		Open:  math.NaN(),
+		High:  math.NaN(),
+		Low:   math.NaN(),
+		Close: math.NaN(),
+	}
+	found := false
+
+	for _, bar := range data {
+		if bar.Date.Before(start) || !bar.Date.Before(end) {
+			continue
+		}
+		if math.IsNaN(agg.Open) && !badPrice(bar.Open) {
+			agg.Open = bar.Open
+		}
+		if !badPrice(bar.High) && !(bar.High <= agg.High) {
+			agg.High = bar.High
+		}
+		if !badPrice(bar.Low) && !(bar.Low >= agg.Low) {
+			agg.Low = bar.Low
+		}
+		if !badPrice(bar.Close) {
+			agg.Close = bar.Close
+		}
+		if !bar.IsMissing(FieldVolume) {
+			agg.Volume += bar.Volume
+		}
+		found = true
+	}
+
+	return agg, found
+}
+
+// rebuildBar replaces the bad values of data[i] with those of agg
+func rebuildBar(data []PriceData, i int, agg PriceData) {
+	bar := &data[i]
+
+	repaired := false
+	for _, field := range []struct {
+		flag    PriceField
+		value   *float64
+		rebuilt float64
+	}{
+		{FieldOpen, &bar.Open, agg.Open},
+		{FieldHigh, &bar.High, agg.High},
+		{FieldLow, &bar.Low, agg.Low},
+		{FieldClose, &bar.Close, agg.Close},
+	} {
+		if badPrice(*field.value) && !math.IsNaN(field.rebuilt) {
+			*field.value = field.rebuilt
+			bar.Missing &^= field.flag
+			repaired = true
+		}
+	}
+
+	if repaired {
+		// Keep the adjustment factor of the nearest bar with both closes
+		if bar.IsMissing(FieldAdjClose) || badPrice(bar.AdjClose) {
+			bar.AdjClose = bar.Close * adjustmentFactorNear(data, i)
+			bar.Missing &^= FieldAdjClose
+		}
+		bar.High = math.Max(bar.High, math.Max(bar.Open, bar.Close))
+		bar.Low = math.Min(bar.Low, math.Min(bar.Open, bar.Close))
+		bar.Repaired |= RepairMissing
+	}
+
+	if isBadVolume(*bar) && agg.Volume > 0 {
+		bar.Volume = agg.Volume
+		bar.Missing &^= FieldVolume
+		bar.Repaired |= RepairVolume
+	}
+}
+
+// adjustmentFactorNear returns AdjClose/Close of the closest bar to i that
+// reports both, preferring later bars, or 1 if there is none
+func adjustmentFactorNear(data []PriceData, i int) float64 {
+	for d := 1; d < len(data); d++ {
+		for _, j := range []int{i + d, i - d} {
+			if j < 0 || j >= len(data) {
+				continue
+			}
+			bar := data[j]
+			if !badPrice(bar.Close) && !badPrice(bar.AdjClose) {
+				return bar.AdjClose / bar.Close
+			}
+		}
+	}
+	return 1
+}
@@ -0,0 +1,276 @@
+package yfinance
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ResampleOption configures HistoryResult.Resample
+type ResampleOption func(*resampleConfig)
+
+// resampleConfig holds the anchoring of resampled buckets
+type resampleConfig struct {
+	weekStart      time.Weekday
+	sessionAligned bool
+}
+
+// WeekStartingOn makes weekly buckets start on day instead of Monday
+func WeekStartingOn(day time.Weekday) ResampleOption {
+	return func(c *resampleConfig) {
+		c.weekStart = day
+	}
+}
+
+// AlignToSession anchors minute and hour buckets at the open of the regular
+// session, so that e.g. 4h bars start at 09:30 rather than 08:00. Days
+// without trading periods use the time of day of the current trading
+// period.
+func AlignToSession() ResampleOption {
+	return func(c *resampleConfig) {
+		c.sessionAligned = true
+	}
+}
+
+// resampleRule is a bucket size: n times a unit of "m", "h", "d", "wk",
+// "mo" or "y"
+type resampleRule struct {
+	n    int
+	unit string
+}
+
+// parseResampleRule parses rules such as "3m", "4h", "2wk" or "3mo"
+func parseResampleRule(rule string) (resampleRule, error) {
+	digits := strings.IndexFunc(rule, func(r rune) bool { return r < '0' || r > '9' })
+	if digits < 0 {
+		return resampleRule{}, fmt.Errorf("invalid resample rule: %q has no unit", rule)
+	}
+
+	r := resampleRule{n: 1, unit: rule[digits:]}
+	if digits > 0 {
+		n, err := strconv.Atoi(rule[:digits])
+		if err != nil || n <= 0 {
+			return resampleRule{}, fmt.Errorf("invalid resample rule: %q", rule)
+		}
+		r.n = n
+	}
+
+	switch r.unit {
+	case "m", "h", "d", "wk", "mo", "y":
+		return r, nil
+	default:
+		return resampleRule{}, fmt.Errorf("invalid resample rule: %q, unit must be one of m, h, d, wk, mo, y", rule)
+	}
+}
+
+// Resample aggregates the bars into buckets of rule, e.g. "3m", "4h", "2wk"
+// or "3mo" for calendar quarters. Buckets are computed in the exchange
+// timezone and labelled with their start. Within a bucket, Open is the
+// first reported open, High and Low the extremes, Close and AdjClose the
+// last reported values and Volume the sum. Dividends and capital gains in
+// a bucket are summed and splits multiplied, dated at the bucket start.
+func (hr *HistoryResult) Resample(rule string, opts ...ResampleOption) (*HistoryResult, error) {
+	r, err := parseResampleRule(rule)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := resampleConfig{weekStart: time.Monday}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	// Bars are already in the exchange timezone, unless built by hand
+	loc := time.UTC
+	if len(hr.Data) > 0 {
+		loc = hr.Data[0].Date.Location()
+	}
+	if hr.Timezone != "" {
+		if parsed, err := time.LoadLocation(hr.Timezone); err == nil {
+			loc = parsed
+		}
+	}
+
+	bucket := func(t time.Time) time.Time {
+		return hr.bucketStart(t.In(loc), r, cfg)
+	}
+
+	out := &HistoryResult{
+		Meta:         hr.Meta,
+		Timezone:     hr.Timezone,
+		Currency:     hr.Currency,
+		Exchange:     hr.Exchange,
+		AutoAdjusted: hr.AutoAdjusted,
+		BackAdjusted: hr.BackAdjusted,
+	}
+
+	bars := make(map[int64]*PriceData)
+	for _, bar := range hr.Data {
+		start := bucket(bar.Date)
+		agg, ok := bars[start.Unix()]
+		if !ok {
+			agg = &PriceData{
+				Date:     start,
+				Open:     math.NaN(),
+				High:     math.NaN(),
+				Low:      math.NaN(),
+				Close:    math.NaN(),
+				AdjClose: math.NaN(),
+				Missing:  FieldAll,
+				Session:  bar.Session,
+			}
+			bars[start.Unix()] = agg
+		}
+		agg.add(bar)
+	}
+	for _, agg := range bars {
+		out.Data = append(out.Data, *agg)
+	}
+	sort.Slice(out.Data, func(i, j int) bool {
+		return out.Data[i].Date.Before(out.Data[j].Date)
+	})
+
+	dividends := make(map[int64]*DividendData)
+	for _, div := range hr.Dividends {
+		start := bucket(div.Date)
+		if agg, ok := dividends[start.Unix()]; ok {
+			agg.Amount += div.Amount
+		} else {
+			dividends[start.Unix()] = &DividendData{Date: start, Amount: div.Amount}
+		}
+	}
+	for _, div := range dividends {
+		out.Dividends = append(out.Dividends, *div)
+	}
+
+	gains := make(map[int64]*CapitalGainData)
+	for _, gain := range hr.CapitalGains {
+		start := bucket(gain.Date)
+		if agg, ok := gains[start.Unix()]; ok {
+			agg.Amount += gain.Amount
+		} else {
+			gains[start.Unix()] = &CapitalGainData{Date: start, Amount: gain.Amount}
+		}
+	}
+	for _, gain := range gains {
+		out.CapitalGains = append(out.CapitalGains, *gain)
+	}
+
+	splits := make(map[int64]*SplitData)
+	for _, split := range hr.Splits {
+		start := bucket(split.Date)
+		if agg, ok := splits[start.Unix()]; ok {
+			agg.Numerator *= split.Numerator
+			agg.Denominator *= split.Denominator
+		} else {
+			splits[start.Unix()] = &SplitData{Date: start, Numerator: split.Numerator, Denominator: split.Denominator}
+		}
+	}
+	for _, split := range splits {
+		split.Ratio = fmt.Sprintf("%.0f:%.0f", split.Numerator, split.Denominator)
+		out.Splits = append(out.Splits, *split)
+	}
+
+	out.sortEvents()
+	return out, nil
+}
+
+// add merges bar into the bucket p
+func (p *PriceData) add(bar PriceData) {
+	if !bar.IsMissing(FieldOpen) && p.IsMissing(FieldOpen) {
+		p.Open = bar.Open
+		p.Missing &^= FieldOpen
+	}
+	if !bar.IsMissing(FieldHigh) && (p.IsMissing(FieldHigh) || bar.High > p.High) {
+		p.High = bar.High
+		p.Missing &^= FieldHigh
+	}
+	if !bar.IsMissing(FieldLow) && (p.IsMissing(FieldLow) || bar.Low < p.Low) {
+		p.Low = bar.Low
+		p.Missing &^= FieldLow
+	}
+	if !bar.IsMissing(FieldClose) {
+		p.Close = bar.Close
+		p.Missing &^= FieldClose
+	}
+	if !bar.IsMissing(FieldAdjClose) {
+		p.AdjClose = bar.AdjClose
+		p.Missing &^= FieldAdjClose
+	}
+	if !bar.IsMissing(FieldVolume) {
+		p.Volume += bar.Volume
+		p.Missing &^= FieldVolume
+	}
+	p.Repaired |= bar.Repaired
+	if p.Session != bar.Session {
+		p.Session = SessionUnknown
+	}
+}
+
+// bucketStart returns the start of the bucket of rule that t falls in. t
+// is in the exchange timezone.
+func (hr *HistoryResult) bucketStart(t time.Time, r resampleRule, cfg resampleConfig) time.Time {
+	loc := t.Location()
+	year, month, day := t.Date()
+	midnight := time.Date(year, month, day, 0, 0, 0, 0, loc)
+	civil := time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Unix() / 86400
+	fromCivil := func(days int64) time.Time {
+		d := time.Unix(days*86400, 0).UTC()
+		return time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, loc)
+	}
+
+	switch r.unit {
+	case "m", "h":
+		step := time.Duration(r.n) * time.Minute
+		if r.unit == "h" {
+			step = time.Duration(r.n) * time.Hour
+		}
+		anchor := midnight
+		if cfg.sessionAligned {
+			if open, ok := hr.Meta.regularOpen(t); ok {
+				anchor = open
+			}
+		}
+		return anchor.Add(time.Duration(floorDiv(int64(t.Sub(anchor)), int64(step))) * step)
+	case "d":
+		return fromCivil(floorDiv(civil, int64(r.n)) * int64(r.n))
+	case "wk":
+		// The Unix epoch is a Thursday
+		first := int64((int(cfg.weekStart) - int(time.Thursday) + 7) % 7)
+		span := int64(7 * r.n)
+		return fromCivil(first + floorDiv(civil-first, span)*span)
+	case "mo":
+		months := int64(year)*12 + int64(month) - 1
+		months = floorDiv(months, int64(r.n)) * int64(r.n)
+		return time.Date(int(months/12), time.Month(months%12+1), 1, 0, 0, 0, 0, loc)
+	default: // "y"
+		return time.Date(int(floorDiv(int64(year), int64(r.n))*int64(r.n)), 1, 1, 0, 0, 0, 0, loc)
+	}
+}
+
+// regularOpen returns the open of the regular session on the day of t
+func (m HistoryMeta) regularOpen(t time.Time) (time.Time, bool) {
+	year, month, day := t.Date()
+	for _, d := range m.TradingPeriods {
+		open := d.Regular.Start.In(t.Location())
+		if y, mo, dd := open.Date(); y == year && mo == month && dd == day {
+			return open, true
+		}
+	}
+	if m.CurrentTradingPeriod.Regular.IsZero() {
+		return time.Time{}, false
+	}
+	return sameTimeOfDay(m.CurrentTradingPeriod, t).Regular.Start, true
+}
+
+// floorDiv divides a by b, rounding towards negative infinity
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
@@ -0,0 +1,160 @@
+package yfinance
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy decides whether and when a failed request is retried
+type RetryPolicy interface {
+	// Backoff is called after every failed attempt. It returns how long to
+	// wait before the next attempt, or false to give up.
+	Backoff(attempt RetryAttempt) (time.Duration, bool)
+}
+
+// RetryAttempt describes a failed attempt
+type RetryAttempt struct {
+	Attempt  int            // Zero-based index of the attempt that failed
+	Elapsed  time.Duration  // Time since the first attempt started
+	Response *http.Response // Response received, nil on network errors
+	Err      error          // Error describing the failure
+}
+
+// ExponentialBackoff is a RetryPolicy using jittered exponential backoff.
+// A Retry-After header on the response takes precedence over the computed
+// delay.
+type ExponentialBackoff struct {
+	MaxRetries int           // Number of retries after the first attempt
+	BaseDelay  time.Duration // Delay before the first retry
+	MaxDelay   time.Duration // Upper bound for the computed delay
+	MaxElapsed time.Duration // Give up once this much time would have passed (0 = no limit)
+	Jitter     float64       // Fraction of the delay that is randomized, between 0 and 1
+	Statuses   map[int]bool  // HTTP statuses that are retried
+}
+
+// DefaultRetryPolicy returns the retry policy used when none is configured
+func DefaultRetryPolicy() *ExponentialBackoff {
+	return &ExponentialBackoff{
+		MaxRetries: 3,
+		BaseDelay:  time.Second,
+		MaxDelay:   30 * time.Second,
+		MaxElapsed: 2 * time.Minute,
+		Jitter:     0.5,
+		Statuses: map[int]bool{
+			http.StatusUnauthorized:        true,
+			http.StatusForbidden:           true,
+			http.StatusTooManyRequests:     true,
+			http.StatusInternalServerError: true,
+			http.StatusBadGateway:          true,
+			http.StatusServiceUnavailable:  true,
+			http.StatusGatewayTimeout:      true,
+		},
+	}
+}
+
+// Backoff implements RetryPolicy
+func (p *ExponentialBackoff) Backoff(a RetryAttempt) (time.Duration, bool) {
+	if a.Attempt >= p.MaxRetries {
+		return 0, false
+	}
+
+	if a.Response != nil {
+		if !p.Statuses[a.Response.StatusCode] {
+			return 0, false
+		}
+	} else if !IsTransientError(a.Err) {
+		return 0, false
+	}
+
+	delay := p.BaseDelay
+	for i := 0; i < a.Attempt && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		jitter := p.Jitter
+		if jitter > 1 {
+			jitter = 1
+		}
+		delay -= time.Duration(rand.Float64() * jitter * float64(delay))
+	}
+
+	if a.Response != nil {
+		if after, ok := parseRetryAfter(a.Response.Header.Get("Retry-After"), time.Now()); ok {
+			delay = after
+		}
+	}
+
+	if p.MaxElapsed > 0 && a.Elapsed+delay > p.MaxElapsed {
+		return 0, false
+	}
+
+	return delay, true
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an
+// HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if at, err := http.ParseTime(value); err == nil {
+		if delay := at.Sub(now); delay > 0 {
+			return delay, true
+		}
+		return 0, true
+	}
+
+	return 0, false
+}
+
+// sleepContext waits for d or until ctx is done, whichever comes first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// RetryObserver is told about every retry of a request, with the attempt
+// that failed and the delay before the next one
+type RetryObserver func(attempt RetryAttempt, delay time.Duration)
+
+// retryObserverKey is the context key of the RetryObserver
+type retryObserverKey struct{}
+
+// WithRetryObserver returns a context that reports the retries of every
+// request made with it to observe
+func WithRetryObserver(ctx context.Context, observe RetryObserver) context.Context {
+	return context.WithValue(ctx, retryObserverKey{}, observe)
+}
+
+// retryObserverFrom returns the RetryObserver of ctx, or nil
+func retryObserverFrom(ctx context.Context) RetryObserver {
+	observe, _ := ctx.Value(retryObserverKey{}).(RetryObserver)
+	return observe
+}
@@ -58,6 +58,11 @@ func WithRecommended(n int) SearchOption {
 
 // NewSearch creates a new Search instance
 func NewSearch(query string, opts ...SearchOption) *Search {
+	return newSearch(query, NewYfData(), opts...)
+}
+
+// newSearch creates a new Search instance using the given session
+func newSearch(query string, data *YfData, opts ...SearchOption) *Search {
 	s := &Search{
 		Query:      query,
 		MaxResults: 8,
@@ -66,7 +71,7 @@ func NewSearch(query string, opts ...SearchOption) *Search {
 		IncludeCB:  true,
 		EnableFuzzy: false,
 		Recommended: 8,
-		data:       NewYfData(),
+		data:       data,
 	}
 
 	for _, opt := range opts {
@@ -90,7 +95,7 @@ func (s *Search) Do(ctx context.Context) error {
 		"newsQueryId":          "news_cie_vespa",
 	}
 
-	endpoint := BaseURL + "/v1/finance/search"
+	endpoint := s.data.endpoints.Query2 + "/v1/finance/search"
 
 	var result searchResponse
 	if err := s.data.GetRawJSON(ctx, endpoint, params, &result); err != nil {
@@ -179,16 +184,21 @@ type Lookup struct {
 
 // NewLookup creates a new Lookup instance
 func NewLookup(query string) *Lookup {
+	return newLookup(query, NewYfData())
+}
+
+// newLookup creates a new Lookup instance using the given session
+func newLookup(query string, data *YfData) *Lookup {
 	return &Lookup{
 		Query: query,
 		Type:  "all",
-		data:  NewYfData(),
+		data:  data,
 	}
 }
 
 // Do executes the lookup
 func (l *Lookup) Do(ctx context.Context) ([]SearchQuote, error) {
-	s := NewSearch(l.Query, WithMaxResults(20))
+	s := newSearch(l.Query, l.data, WithMaxResults(20))
 	if err := s.Do(ctx); err != nil {
 		return nil, err
 	}
@@ -0,0 +1,189 @@
+package yfinance
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// Session identifies the trading session a bar belongs to
+type Session uint8
+
+const (
+	// SessionUnknown is the session of bars that were not tagged
+	SessionUnknown Session = iota
+	// SessionPre is pre-market trading
+	SessionPre
+	// SessionRegular is regular market hours
+	SessionRegular
+	// SessionPost is after-hours trading
+	SessionPost
+	// SessionOvernight is overnight trading, outside the pre, regular and
+	// post sessions. Only streamed ticks report it.
+	SessionOvernight
+)
+
+// String returns the name of the session
+func (s Session) String() string {
+	switch s {
+	case SessionPre:
+		return "pre"
+	case SessionRegular:
+		return "regular"
+	case SessionPost:
+		return "post"
+	case SessionOvernight:
+		return "overnight"
+	default:
+		return "unknown"
+	}
+}
+
+// TradingPeriod is the window of one trading session
+type TradingPeriod struct {
+	Timezone  string
+	Start     time.Time
+	End       time.Time
+	Gmtoffset int
+}
+
+// IsZero reports whether the period was not reported
+func (p TradingPeriod) IsZero() bool {
+	return p.Start.IsZero() && p.End.IsZero()
+}
+
+// Contains reports whether t falls within the period
+func (p TradingPeriod) Contains(t time.Time) bool {
+	return !t.Before(p.Start) && t.Before(p.End)
+}
+
+// TradingDay holds the sessions of one trading day. Pre and Post are zero
+// when Yahoo did not report them, e.g. without HistoryOptions.PrePost.
+type TradingDay struct {
+	Pre     TradingPeriod
+	Regular TradingPeriod
+	Post    TradingPeriod
+}
+
+// Session returns the session of the day t falls in, or SessionUnknown if
+// it is outside every reported session
+func (d TradingDay) Session(t time.Time) Session {
+	switch {
+	case d.Regular.Contains(t):
+		return SessionRegular
+	case d.Pre.Contains(t):
+		return SessionPre
+	case d.Post.Contains(t):
+		return SessionPost
+	default:
+		return SessionUnknown
+	}
+}
+
+// chartTradingPeriod is a session window as Yahoo reports it
+type chartTradingPeriod struct {
+	Timezone  string `json:"timezone"`
+	Start     int64  `json:"start"`
+	End       int64  `json:"end"`
+	Gmtoffset int    `json:"gmtoffset"`
+}
+
+// period converts p to a TradingPeriod in loc
+func (p chartTradingPeriod) period(loc *time.Location) TradingPeriod {
+	if p.Start == 0 && p.End == 0 {
+		return TradingPeriod{}
+	}
+	return TradingPeriod{
+		Timezone:  p.Timezone,
+		Start:     time.Unix(p.Start, 0).In(loc),
+		End:       time.Unix(p.End, 0).In(loc),
+		Gmtoffset: p.Gmtoffset,
+	}
+}
+
+// chartCurrentTradingPeriod holds the sessions of the current trading day
+type chartCurrentTradingPeriod struct {
+	Pre     chartTradingPeriod `json:"pre"`
+	Regular chartTradingPeriod `json:"regular"`
+	Post    chartTradingPeriod `json:"post"`
+}
+
+// chartTradingPeriods holds the sessions of every day in an intraday chart.
+// Yahoo sends a list of regular sessions per day, or, with includePrePost,
+// an object holding the pre, regular and post lists.
+type chartTradingPeriods struct {
+	Pre     [][]chartTradingPeriod `json:"pre"`
+	Regular [][]chartTradingPeriod `json:"regular"`
+	Post    [][]chartTradingPeriod `json:"post"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (p *chartTradingPeriods) UnmarshalJSON(data []byte) error {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		return json.Unmarshal(trimmed, &p.Regular)
+	}
+	type sessions chartTradingPeriods
+	return json.Unmarshal(data, (*sessions)(p))
+}
+
+// days converts the periods to one TradingDay per day in loc
+func (p *chartTradingPeriods) days(loc *time.Location) []TradingDay {
+	// span merges the windows Yahoo reports for one day, e.g. around a
+	// lunch break, into one
+	span := func(days [][]chartTradingPeriod, i int) TradingPeriod {
+		if i >= len(days) || len(days[i]) == 0 {
+			return TradingPeriod{}
+		}
+		windows := days[i]
+		merged := windows[0]
+		merged.End = windows[len(windows)-1].End
+		return merged.period(loc)
+	}
+
+	days := make([]TradingDay, 0, len(p.Regular))
+	for i := range p.Regular {
+		days = append(days, TradingDay{
+			Pre:     span(p.Pre, i),
+			Regular: span(p.Regular, i),
+			Post:    span(p.Post, i),
+		})
+	}
+	return days
+}
+
+// tagSessions sets the session of every bar from the trading periods in
+// meta. Bars on days without reported periods are tagged from the time of
+// day of the current trading period.
+func tagSessions(data []PriceData, meta HistoryMeta) {
+	current := meta.CurrentTradingPeriod
+	for i := range data {
+		bar := &data[i]
+		session := SessionUnknown
+		for _, day := range meta.TradingPeriods {
+			if session = day.Session(bar.Date); session != SessionUnknown {
+				break
+			}
+		}
+		if session == SessionUnknown {
+			session = sameTimeOfDay(current, bar.Date).Session(bar.Date)
+		}
+		bar.Session = session
+	}
+}
+
+// sameTimeOfDay moves the sessions of day to the date of t, keeping their
+// wall clock times in the location of t
+func sameTimeOfDay(day TradingDay, t time.Time) TradingDay {
+	move := func(p TradingPeriod) TradingPeriod {
+		if p.IsZero() {
+			return p
+		}
+		start, end := p.Start.In(t.Location()), p.End.In(t.Location())
+		year, month, date := t.Date()
+		length := end.Sub(start)
+		p.Start = time.Date(year, month, date, start.Hour(), start.Minute(), start.Second(), 0, t.Location())
+		p.End = p.Start.Add(length)
+		return p
+	}
+	return TradingDay{Pre: move(day.Pre), Regular: move(day.Regular), Post: move(day.Post)}
+}
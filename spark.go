@@ -0,0 +1,87 @@
+package yfinance
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// SparkBatchSize is the number of symbols Yahoo's spark endpoint accepts
+// per request
+const SparkBatchSize = 20
+
+// sparkResponse represents the Yahoo Finance spark API response
+type sparkResponse struct {
+	Spark struct {
+		Result []struct {
+			Symbol   string        `json:"symbol"`
+			Response []chartResult `json:"response"`
+		} `json:"result"`
+		Error *struct {
+			Code        string `json:"code"`
+			Description string `json:"description"`
+		} `json:"error"`
+	} `json:"spark"`
+}
+
+// useSpark reports whether a download can be served from spark batches:
+// close-only data over a period that fits in one request, without
+// adjustment or repair, which need the full bars and events
+func useSpark(options *DownloadOptions, histOpts *HistoryOptions) bool {
+	if !options.CloseOnly {
+		return false
+	}
+	if histOpts.AutoAdjust || histOpts.BackAdjust || histOpts.Repair || histOpts.PrePost {
+		return false
+	}
+	if histOpts.Start != nil || histOpts.End != nil {
+		return false
+	}
+	chunks, err := historyChunks("", histOpts, time.Now())
+	return err == nil && chunks == nil
+}
+
+// fetchSpark fetches the closes of symbols in one spark request. Symbols
+// Yahoo has no data for are left out of the result.
+func fetchSpark(ctx context.Context, data *YfData, symbols []string, options *HistoryOptions) (map[string]*HistoryResult, error) {
+	params := map[string]string{
+		"symbols": strings.Join(symbols, ","),
+	}
+	if options.Period != "" {
+		params["range"] = options.Period
+	}
+	if options.Interval != "" {
+		params["interval"] = options.Interval
+	}
+
+	var resp sparkResponse
+	if err := data.GetRawJSON(ctx, data.endpoints.Query1+"/v7/finance/spark", params, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Spark.Error != nil {
+		return nil, fmt.Errorf("spark error: %s", resp.Spark.Error.Description)
+	}
+
+	results := make(map[string]*HistoryResult, len(resp.Spark.Result))
+	for _, r := range resp.Spark.Result {
+		if len(r.Response) == 0 || len(r.Response[0].Timestamp) == 0 {
+			continue
+		}
+
+		t := NewTickerWithData(r.Symbol, data)
+		hr, err := t.parseChartResult(r.Response[0], options)
+		if err != nil {
+			continue
+		}
+		// Spark has no adjusted closes; parseChartResult would copy Close
+		for i := range hr.Data {
+			hr.Data[i].AdjClose = math.NaN()
+			hr.Data[i].Missing |= FieldAdjClose
+		}
+		t.postProcess(ctx, hr, r.Response[0].Meta, options)
+		results[strings.ToUpper(r.Symbol)] = hr
+	}
+	return results, nil
+}
@@ -0,0 +1,264 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strconv"
+	"time"
+
+	yf "github.com/FFengIll/yfinance-go"
+)
+
+// file is the on-disk format of one symbol and interval. Bars are stored
+// column by column, the same layout Yahoo's chart API uses.
+type file struct {
+	Symbol    string `json:"symbol"`
+	Interval  string `json:"interval"`
+	Timezone  string `json:"timezone,omitempty"`
+	Currency  string `json:"currency,omitempty"`
+	Exchange  string `json:"exchange,omitempty"`
+	UpdatedAt int64  `json:"updatedAt"`
+
+	Timestamp []int64 `json:"timestamp"`
+	Open      column  `json:"open"`
+	High      column  `json:"high"`
+	Low       column  `json:"low"`
+	Close     column  `json:"close"`
+	AdjClose  column  `json:"adjclose"`
+	Volume    column  `json:"volume"`
+
+	Dividends    []dividend `json:"dividends,omitempty"`
+	Splits       []split    `json:"splits,omitempty"`
+	CapitalGains []dividend `json:"capitalGains,omitempty"`
+}
+
+// dividend is a stored dividend or capital gain event
+type dividend struct {
+	Date   int64   `json:"date"`
+	Amount float64 `json:"amount"`
+}
+
+// split is a stored split event
+type split struct {
+	Date        int64   `json:"date"`
+	Numerator   float64 `json:"numerator"`
+	Denominator float64 `json:"denominator"`
+}
+
+// column is a price column. NaN values are stored as null.
+type column []float64
+
+// MarshalJSON implements json.Marshaler
+func (c column) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, v := range c {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			buf.WriteString("null")
+		} else {
+			buf.WriteString(strconv.FormatFloat(v, 'g', -1, 64))
+		}
+	}
+	buf.WriteByte(']')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (c *column) UnmarshalJSON(data []byte) error {
+	var values []*float64
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	*c = make(column, len(values))
+	for i, v := range values {
+		if v == nil {
+			(*c)[i] = math.NaN()
+		} else {
+			(*c)[i] = *v
+		}
+	}
+	return nil
+}
+
+// len returns the number of bars in the file
+func (f *file) len() int {
+	return len(f.Timestamp)
+}
+
+// appendBar appends a bar to every column
+func (f *file) appendBar(bar yf.PriceData) {
+	f.Timestamp = append(f.Timestamp, bar.Date.Unix())
+	f.Open = append(f.Open, bar.Open)
+	f.High = append(f.High, bar.High)
+	f.Low = append(f.Low, bar.Low)
+	f.Close = append(f.Close, bar.Close)
+	f.AdjClose = append(f.AdjClose, bar.AdjClose)
+	f.Volume = append(f.Volume, volume(bar))
+}
+
+// setBar replaces the bar at index i
+func (f *file) setBar(i int, bar yf.PriceData) {
+	f.Timestamp[i] = bar.Date.Unix()
+	f.Open[i] = bar.Open
+	f.High[i] = bar.High
+	f.Low[i] = bar.Low
+	f.Close[i] = bar.Close
+	f.AdjClose[i] = bar.AdjClose
+	f.Volume[i] = volume(bar)
+}
+
+// volume returns the volume of bar as a column value
+func volume(bar yf.PriceData) float64 {
+	if bar.IsMissing(yf.FieldVolume) {
+		return math.NaN()
+	}
+	return float64(bar.Volume)
+}
+
+// valid reports whether every column has one value per timestamp
+func (f *file) valid() bool {
+	n := f.len()
+	return len(f.Open) == n && len(f.High) == n && len(f.Low) == n &&
+		len(f.Close) == n && len(f.AdjClose) == n && len(f.Volume) == n
+}
+
+// addEvents merges the dividends, splits and capital gains of hr into the
+// file
+func (f *file) addEvents(hr *yf.HistoryResult) {
+	for _, d := range hr.Dividends {
+		if !hasEvent(f.Dividends, d.Date.Unix()) {
+			f.Dividends = append(f.Dividends, dividend{Date: d.Date.Unix(), Amount: d.Amount})
+		}
+	}
+	for _, g := range hr.CapitalGains {
+		if !hasEvent(f.CapitalGains, g.Date.Unix()) {
+			f.CapitalGains = append(f.CapitalGains, dividend{Date: g.Date.Unix(), Amount: g.Amount})
+		}
+	}
+	for _, s := range hr.Splits {
+		if !f.hasSplit(s.Date.Unix()) {
+			f.Splits = append(f.Splits, split{Date: s.Date.Unix(), Numerator: s.Numerator, Denominator: s.Denominator})
+		}
+	}
+}
+
+// hasEvent reports whether events has one on date
+func hasEvent(events []dividend, date int64) bool {
+	for _, d := range events {
+		if d.Date == date {
+			return true
+		}
+	}
+	return false
+}
+
+// hasSplit reports whether a split on date is stored
+func (f *file) hasSplit(date int64) bool {
+	for _, s := range f.Splits {
+		if s.Date == date {
+			return true
+		}
+	}
+	return false
+}
+
+// newFile builds a file from a full history fetch
+func newFile(symbol, interval string, hr *yf.HistoryResult) *file {
+	f := &file{Symbol: symbol, Interval: interval}
+	f.setMeta(hr)
+	for _, bar := range hr.Data {
+		f.appendBar(bar)
+	}
+	f.addEvents(hr)
+	return f
+}
+
+// setMeta copies the metadata of hr into the file
+func (f *file) setMeta(hr *yf.HistoryResult) {
+	if hr.Timezone != "" {
+		f.Timezone = hr.Timezone
+	}
+	if hr.Currency != "" {
+		f.Currency = hr.Currency
+	}
+	if hr.Exchange != "" {
+		f.Exchange = hr.Exchange
+	}
+	f.UpdatedAt = time.Now().Unix()
+}
+
+// history converts the file to a HistoryResult
+func (f *file) history() *yf.HistoryResult {
+	loc := time.UTC
+	if f.Timezone != "" {
+		if parsed, err := time.LoadLocation(f.Timezone); err == nil {
+			loc = parsed
+		}
+	}
+
+	hr := &yf.HistoryResult{
+		Meta: yf.HistoryMeta{
+			Symbol:       f.Symbol,
+			Currency:     f.Currency,
+			ExchangeName: f.Exchange,
+			Timezone:     f.Timezone,
+		},
+		Timezone: f.Timezone,
+		Currency: f.Currency,
+		Exchange: f.Exchange,
+		Data:     make([]yf.PriceData, f.len()),
+	}
+
+	for i, ts := range f.Timestamp {
+		bar := yf.PriceData{
+			Date:     time.Unix(ts, 0).In(loc),
+			Open:     f.Open[i],
+			High:     f.High[i],
+			Low:      f.Low[i],
+			Close:    f.Close[i],
+			AdjClose: f.AdjClose[i],
+		}
+		for field, v := range map[yf.PriceField]float64{
+			yf.FieldOpen:     bar.Open,
+			yf.FieldHigh:     bar.High,
+			yf.FieldLow:      bar.Low,
+			yf.FieldClose:    bar.Close,
+			yf.FieldAdjClose: bar.AdjClose,
+			yf.FieldVolume:   f.Volume[i],
+		} {
+			if math.IsNaN(v) {
+				bar.Missing |= field
+			}
+		}
+		if !bar.IsMissing(yf.FieldVolume) {
+			bar.Volume = int64(f.Volume[i])
+		}
+		hr.Data[i] = bar
+	}
+	for _, d := range f.Dividends {
+		hr.Dividends = append(hr.Dividends, yf.DividendData{
+			Date:   time.Unix(d.Date, 0).In(loc),
+			Amount: d.Amount,
+		})
+	}
+	for _, s := range f.Splits {
+		hr.Splits = append(hr.Splits, yf.SplitData{
+			Date:        time.Unix(s.Date, 0).In(loc),
+			Numerator:   s.Numerator,
+			Denominator: s.Denominator,
+			Ratio:       strconv.FormatFloat(s.Numerator, 'f', 0, 64) + ":" + strconv.FormatFloat(s.Denominator, 'f', 0, 64),
+		})
+	}
+	for _, g := range f.CapitalGains {
+		hr.CapitalGains = append(hr.CapitalGains, yf.CapitalGainData{
+			Date:   time.Unix(g.Date, 0).In(loc),
+			Amount: g.Amount,
+		})
+	}
+
+	return hr
+}
@@ -0,0 +1,361 @@
+// Package store keeps a local copy of price history and brings it up to
+// date with incremental syncs.
+//
+// Bars, dividends and splits are stored per symbol and interval as one
+// columnar JSON file. Sync only downloads the bars after the last stored
+// one, and rewrites the whole history when a new split or dividend makes
+// the stored adjusted prices stale, or when intraday bars were last synced
+// before the interval's lookback.
+//
+// Basic Usage:
+//
+//	s, err := store.Open("prices", yf.NewClient())
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//
+//	results, err := s.Sync(ctx, []string{"AAPL", "MSFT"}, "1d")
+//	history, err := s.Load("AAPL", "1d")
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	yf "github.com/FFengIll/yfinance-go"
+)
+
+// ErrNotFound is returned by Load when nothing is stored for a symbol and
+// interval
+var ErrNotFound = errors.New("no stored history")
+
+// adjustmentTolerance is the relative difference between the stored and
+// fetched adjustment factor of a bar above which the history is rewritten
+const adjustmentTolerance = 1e-6
+
+// Store is a local price history store. It is safe for concurrent use.
+type Store struct {
+	dir         string
+	client      *yf.Client
+	concurrency int
+
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// Option is a functional option for Store
+type Option func(*Store)
+
+// WithConcurrency sets how many symbols Sync fetches at once (default 4)
+func WithConcurrency(n int) Option {
+	return func(s *Store) {
+		if n > 0 {
+			s.concurrency = n
+		}
+	}
+}
+
+// SyncResult describes the outcome of syncing one symbol
+type SyncResult struct {
+	Symbol    string
+	Added     int   // Number of bars after the previously stored last bar
+	Rewritten bool  // Whether the whole history was downloaded again
+	Err       error // Error that stopped the sync of this symbol
+}
+
+// Open opens the store in dir, creating it if needed. A nil client uses
+// yf.NewClient().
+func Open(dir string, client *yf.Client, opts ...Option) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	if client == nil {
+		client = yf.NewClient()
+	}
+
+	s := &Store{
+		dir:         dir,
+		client:      client,
+		concurrency: 4,
+		locks:       make(map[string]*sync.Mutex),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+// Load returns the stored history of symbol at interval
+func (s *Store) Load(symbol, interval string) (*yf.HistoryResult, error) {
+	symbol = normalizeSymbol(symbol)
+	unlock := s.lock(symbol, interval)
+	defer unlock()
+
+	f, err := s.read(symbol, interval)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%s %s: %w", symbol, interval, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return f.history(), nil
+}
+
+// Delete removes the stored history of symbol at interval
+func (s *Store) Delete(symbol, interval string) error {
+	symbol = normalizeSymbol(symbol)
+	unlock := s.lock(symbol, interval)
+	defer unlock()
+
+	err := os.Remove(s.path(symbol, interval))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Sync brings the stored history of every symbol at interval up to date.
+// Results are returned in the order of symbols; the error joins the
+// errors of every symbol that failed.
+func (s *Store) Sync(ctx context.Context, symbols []string, interval string) ([]SyncResult, error) {
+	results := make([]SyncResult, len(symbols))
+	sem := make(chan struct{}, s.concurrency)
+	var wg sync.WaitGroup
+
+	for i, symbol := range symbols {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				results[i] = SyncResult{Symbol: normalizeSymbol(symbol), Err: ctx.Err()}
+				return
+			}
+
+			results[i] = s.syncSymbol(ctx, normalizeSymbol(symbol), interval)
+		}()
+	}
+	wg.Wait()
+
+	var errs []error
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.Symbol, r.Err))
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+// syncSymbol syncs a single symbol
+func (s *Store) syncSymbol(ctx context.Context, symbol, interval string) SyncResult {
+	unlock := s.lock(symbol, interval)
+	defer unlock()
+
+	result := SyncResult{Symbol: symbol}
+
+	stored, err := s.read(symbol, interval)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		result.Err = err
+		return result
+	}
+	if stored == nil || stored.len() == 0 {
+		return s.rewrite(ctx, symbol, interval)
+	}
+
+	last := stored.Timestamp[stored.len()-1]
+	start := time.Unix(last, 0)
+	end := time.Now()
+	hr, err := s.client.Ticker(symbol).History(ctx, &yf.HistoryOptions{
+		Interval: interval,
+		Start:    &start,
+		End:      &end,
+	})
+	// Intraday bars older than the interval's lookback are gone, so a store
+	// last synced before it can only start over
+	var lookback *yf.YFLookbackError
+	if errors.As(err, &lookback) {
+		return s.rewrite(ctx, symbol, interval)
+	}
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	if invalidated(stored, hr) {
+		return s.rewrite(ctx, symbol, interval)
+	}
+
+	// Refresh the last stored bar, which may have been incomplete, and
+	// append everything after it
+	lastIndex := stored.len() - 1
+	for _, bar := range hr.Data {
+		switch ts := bar.Date.Unix(); {
+		case ts == last:
+			stored.setBar(lastIndex, bar)
+		case ts > last:
+			stored.appendBar(bar)
+			result.Added++
+		}
+	}
+	stored.addEvents(hr)
+	stored.setMeta(hr)
+
+	result.Err = s.write(stored)
+	return result
+}
+
+// rewrite downloads the full history of symbol and replaces the stored one
+func (s *Store) rewrite(ctx context.Context, symbol, interval string) SyncResult {
+	result := SyncResult{Symbol: symbol, Rewritten: true}
+
+	hr, err := s.client.Ticker(symbol).History(ctx, &yf.HistoryOptions{
+		Period:   initialPeriod(interval),
+		Interval: interval,
+	})
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	f := newFile(symbol, interval, hr)
+	result.Added = f.len()
+	result.Err = s.write(f)
+	return result
+}
+
+// invalidated reports whether hr shows that the stored adjusted prices are
+// stale: a split, dividend or capital gain that is not stored yet, or a
+// different adjustment factor for the bar both cover
+func invalidated(stored *file, hr *yf.HistoryResult) bool {
+	for _, d := range hr.Dividends {
+		if !hasEvent(stored.Dividends, d.Date.Unix()) {
+			return true
+		}
+	}
+	for _, g := range hr.CapitalGains {
+		if !hasEvent(stored.CapitalGains, g.Date.Unix()) {
+			return true
+		}
+	}
+	for _, s := range hr.Splits {
+		if !stored.hasSplit(s.Date.Unix()) {
+			return true
+		}
+	}
+
+	i := stored.len() - 1
+	last := stored.Timestamp[i]
+	for _, bar := range hr.Data {
+		if bar.Date.Unix() != last {
+			continue
+		}
+		storedFactor := stored.AdjClose[i] / stored.Close[i]
+		fetchedFactor := bar.AdjClose / bar.Close
+		if math.IsNaN(storedFactor) || math.IsNaN(fetchedFactor) || math.IsInf(storedFactor, 0) || math.IsInf(fetchedFactor, 0) {
+			return false
+		}
+		return math.Abs(storedFactor-fetchedFactor) > adjustmentTolerance*math.Abs(storedFactor)
+	}
+	return false
+}
+
+// initialPeriod returns the longest period Yahoo serves for interval
+func initialPeriod(interval string) string {
+	switch interval {
+	case "1m":
+		return "5d"
+	case "2m", "5m", "15m", "30m", "90m":
+		return "1mo"
+	case "60m", "1h":
+		return "2y"
+	default:
+		return "max"
+	}
+}
+
+// read loads the stored file of symbol at interval
+func (s *Store) read(symbol, interval string) (*file, error) {
+	data, err := os.ReadFile(s.path(symbol, interval))
+	if err != nil {
+		return nil, err
+	}
+
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("corrupt store file for %s %s: %w", symbol, interval, err)
+	}
+	if !f.valid() {
+		return nil, fmt.Errorf("corrupt store file for %s %s: column lengths differ", symbol, interval)
+	}
+	return &f, nil
+}
+
+// write atomically replaces the stored file
+func (s *Store) write(f *file) error {
+	path := s.path(f.Symbol, f.Interval)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// path returns the file holding symbol at interval
+func (s *Store) path(symbol, interval string) string {
+	return filepath.Join(s.dir, url.PathEscape(symbol), url.PathEscape(interval)+".json")
+}
+
+// lock locks symbol at interval and returns the unlock function
+func (s *Store) lock(symbol, interval string) func() {
+	key := symbol + " " + interval
+
+	s.mu.Lock()
+	l, ok := s.locks[key]
+	if !ok {
+		l = &sync.Mutex{}
+		s.locks[key] = l
+	}
+	s.mu.Unlock()
+
+	l.Lock()
+	return l.Unlock
+}
+
+// normalizeSymbol normalizes a symbol the way yf.NewTicker does
+func normalizeSymbol(symbol string) string {
+	return strings.ToUpper(strings.TrimSpace(symbol))
+}
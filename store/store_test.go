@@ -0,0 +1,184 @@
+package store_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+	"testing/fstest"
+
+	"github.com/FFengIll/yfinance-go/store"
+	"github.com/FFengIll/yfinance-go/yfinancetest"
+)
+
+// day is the timestamp of the first fixture bar; bars are one day apart
+const day = int64(1715002200)
+
+// chartFixture builds a daily chart response with n bars closing at 100,
+// 101, ... and the given dividends, keyed by bar index
+func chartFixture(n int, dividends map[int]float64) []byte {
+	var timestamps []int64
+	var closes, adjCloses []float64
+	var volumes []int64
+	for i := 0; i < n; i++ {
+		timestamps = append(timestamps, day+int64(i)*86400)
+		closes = append(closes, 100+float64(i))
+		volumes = append(volumes, 1000)
+	}
+
+	// Adjusted closes before each dividend are scaled down, like Yahoo does
+	for i := range closes {
+		factor := 1.0
+		for at, amount := range dividends {
+			if i < at {
+				factor *= 1 - amount/closes[at-1]
+			}
+		}
+		adjCloses = append(adjCloses, closes[i]*factor)
+	}
+
+	events := map[string]interface{}{}
+	if len(dividends) > 0 {
+		divs := map[string]interface{}{}
+		for at, amount := range dividends {
+			ts := strconv.FormatInt(timestamps[at], 10)
+			divs[ts] = map[string]interface{}{"amount": amount, "date": timestamps[at]}
+		}
+		events["dividends"] = divs
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"chart": map[string]interface{}{
+			"result": []interface{}{map[string]interface{}{
+				"meta": map[string]interface{}{
+					"symbol":   "AAPL",
+					"currency": "USD",
+					"timezone": "UTC",
+				},
+				"timestamp": timestamps,
+				"events":    events,
+				"indicators": map[string]interface{}{
+					"quote": []interface{}{map[string]interface{}{
+						"open":   closes,
+						"high":   closes,
+						"low":    closes,
+						"close":  closes,
+						"volume": volumes,
+					}},
+					"adjclose": []interface{}{map[string]interface{}{
+						"adjclose": adjCloses,
+					}},
+				},
+			}},
+		},
+	})
+	return data
+}
+
+// syncWith syncs daily AAPL bars against a server serving chart
+func syncWith(t *testing.T, dir string, chart []byte) store.SyncResult {
+	t.Helper()
+	return syncInterval(t, dir, "1d", chart)
+}
+
+// syncInterval syncs AAPL at interval against a server serving chart
+func syncInterval(t *testing.T, dir, interval string, chart []byte) store.SyncResult {
+	t.Helper()
+
+	server := yfinancetest.NewServer(yfinancetest.WithFixtures(fstest.MapFS{
+		"chart/AAPL.json": {Data: chart},
+	}))
+	defer server.Close()
+
+	s, err := store.Open(dir, server.Client())
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	results, err := s.Sync(context.Background(), []string{"AAPL"}, interval)
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+	return results[0]
+}
+
+func TestSyncIncremental(t *testing.T) {
+	dir := t.TempDir()
+
+	result := syncWith(t, dir, chartFixture(3, nil))
+	if !result.Rewritten || result.Added != 3 {
+		t.Errorf("Expected an initial download of 3 bars, got %+v", result)
+	}
+
+	result = syncWith(t, dir, chartFixture(5, nil))
+	if result.Rewritten || result.Added != 2 {
+		t.Errorf("Expected 2 bars to be appended, got %+v", result)
+	}
+
+	s, _ := store.Open(dir, nil)
+	history, err := s.Load("AAPL", "1d")
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	if len(history.Data) != 5 {
+		t.Fatalf("Expected 5 bars, got %d", len(history.Data))
+	}
+	for i, bar := range history.Data {
+		if bar.Date.Unix() != day+int64(i)*86400 || bar.Close != 100+float64(i) {
+			t.Errorf("Unexpected bar %d: %+v", i, bar)
+		}
+	}
+}
+
+func TestSyncRewritesOnNewDividend(t *testing.T) {
+	dir := t.TempDir()
+
+	syncWith(t, dir, chartFixture(4, nil))
+
+	result := syncWith(t, dir, chartFixture(6, map[int]float64{5: 1.04}))
+	if !result.Rewritten || result.Added != 6 {
+		t.Errorf("Expected the history to be rewritten, got %+v", result)
+	}
+
+	s, _ := store.Open(dir, nil)
+	history, err := s.Load("AAPL", "1d")
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	if len(history.Dividends) != 1 || history.Dividends[0].Amount != 1.04 {
+		t.Errorf("Expected the dividend to be stored, got %+v", history.Dividends)
+	}
+	if expected := 100 * (1 - 1.04/104); math.Abs(history.Data[0].AdjClose-expected) > 1e-9 {
+		t.Errorf("Expected stored adjusted close %f, got %f", expected, history.Data[0].AdjClose)
+	}
+
+	// A sync without changes keeps the stored history
+	result = syncWith(t, dir, chartFixture(6, map[int]float64{5: 1.04}))
+	if result.Rewritten || result.Added != 0 {
+		t.Errorf("Expected nothing to change, got %+v", result)
+	}
+}
+
+func TestSyncRewritesStaleIntraday(t *testing.T) {
+	dir := t.TempDir()
+
+	syncInterval(t, dir, "1m", chartFixture(3, nil))
+
+	// The stored bars are far older than the 1m lookback, so fetching
+	// from the last one is rejected and the history starts over
+	result := syncInterval(t, dir, "1m", chartFixture(4, nil))
+	if result.Err != nil || !result.Rewritten || result.Added != 4 {
+		t.Errorf("Expected the stale history to be rewritten, got %+v", result)
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	s, err := store.Open(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	if _, err := s.Load("AAPL", "1d"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package yfinance
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"time"
+)
+
+// HistoryIter returns an iterator over the bars History would return. Ranges
+// fetched in chunks (see IntervalLimits) are fetched, decoded and yielded
+// one chunk at a time, so that only one chunk is held in memory. Other
+// ranges are fetched in a single request.
+//
+// Repair and rounding are applied per chunk. AutoAdjust stays exact, since
+// every chunk carries the adjustment of later dividends in AdjClose;
+// BackAdjust fetches the splits of the whole range first. An error ends the
+// iteration after it is yielded.
+func (t *Ticker) HistoryIter(ctx context.Context, options *HistoryOptions) iter.Seq2[PriceData, error] {
+	return func(yield func(PriceData, error) bool) {
+		if options == nil {
+			options = DefaultHistoryOptions()
+		}
+		if err := options.Validate(); err != nil {
+			yield(PriceData{}, err)
+			return
+		}
+
+		t.GetTimezone(ctx)
+
+		chunks, err := historyChunks(t.Symbol, options, time.Now())
+		if err != nil {
+			yield(PriceData{}, err)
+			return
+		}
+
+		if len(chunks) == 0 {
+			hr, err := t.History(ctx, options)
+			if err != nil {
+				yield(PriceData{}, err)
+				return
+			}
+			for _, bar := range hr.Data {
+				if !yield(bar, nil) {
+					return
+				}
+			}
+			return
+		}
+
+		// A chunk only reports its own splits, but back-adjusting a bar
+		// needs every split after it
+		var splits []SplitData
+		if options.BackAdjust && !options.AutoAdjust {
+			if splits, err = t.splitsBetween(ctx, chunks[0].start, chunks[len(chunks)-1].end); err != nil {
+				yield(PriceData{}, err)
+				return
+			}
+		}
+
+		found := false
+		var last time.Time
+		for _, chunk := range chunks {
+			chunkOptions := *options
+			chunkOptions.Period = ""
+			chunkOptions.Start = &chunk.start
+			chunkOptions.End = &chunk.end
+
+			hr, meta, err := t.fetchChart(ctx, &chunkOptions)
+			var missing *YFPricesMissingError
+			if errors.As(err, &missing) {
+				continue
+			}
+			if err != nil {
+				yield(PriceData{}, err)
+				return
+			}
+			found = true
+
+			if splits != nil {
+				hr.Splits = splits
+			}
+			t.postProcess(ctx, hr, meta, &chunkOptions)
+
+			// Bars on a chunk boundary are returned by both chunks
+			for _, bar := range hr.Data {
+				if !bar.Date.After(last) {
+					continue
+				}
+				last = bar.Date
+				if !yield(bar, nil) {
+					return
+				}
+			}
+		}
+
+		if !found {
+			yield(PriceData{}, NewYFPricesMissingError(t.Symbol, ""))
+		}
+	}
+}
+
+// splitsBetween fetches the splits of the ticker from start to end
+func (t *Ticker) splitsBetween(ctx context.Context, start, end time.Time) ([]SplitData, error) {
+	hr, _, err := t.fetchChart(ctx, &HistoryOptions{
+		Interval: "1d",
+		Start:    &start,
+		End:      &end,
+	})
+	var missing *YFPricesMissingError
+	if errors.As(err, &missing) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return hr.Splits, nil
+}
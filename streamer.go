@@ -0,0 +1,374 @@
+package yfinance
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// StreamerOption is a functional option for Streamer
+type StreamerOption func(*Streamer)
+
+// WithStreamerURL overrides the streamer endpoint of the session, for
+// example to connect to a local stand-in
+func WithStreamerURL(url string) StreamerOption {
+	return func(s *Streamer) {
+		s.url = url
+	}
+}
+
+// WithReconnectPolicy replaces the default reconnection backoff, which
+// retries forever with delays doubling from one second up to 30 seconds.
+// The attempt count restarts once a connection succeeds.
+func WithReconnectPolicy(policy RetryPolicy) StreamerOption {
+	return func(s *Streamer) {
+		s.policy = policy
+	}
+}
+
+// WithTickBuffer sets the capacity of the tick channel (256 by default).
+// The streamer stops reading from the connection while the channel is full.
+func WithTickBuffer(n int) StreamerOption {
+	return func(s *Streamer) {
+		s.buffer = n
+	}
+}
+
+// WithStreamerErrorHandler is called with every connection and decoding
+// error. The streamer recovers from them on its own.
+func WithStreamerErrorHandler(handle func(error)) StreamerOption {
+	return func(s *Streamer) {
+		s.onError = handle
+	}
+}
+
+// Streamer delivers live price ticks from Yahoo's WebSocket streamer.
+// Symbols can be subscribed and unsubscribed at any time, before or while
+// streaming. When the connection drops, the streamer reconnects and
+// subscribes to every symbol again.
+//
+// A Streamer connects with the proxy, endpoints and user agent of the
+// session it was created from.
+type Streamer struct {
+	url       string
+	origin    string
+	userAgent string
+	proxy     string
+	policy    RetryPolicy
+	buffer    int
+	onError   func(error)
+
+	mu        sync.Mutex
+	symbols   map[string]bool
+	conn      *websocket.Conn
+	streaming bool
+}
+
+// NewStreamer creates a Streamer configured from GlobalConfig. It does not
+// connect until Stream is called.
+func NewStreamer(opts ...StreamerOption) *Streamer {
+	return newStreamer(NewYfData(), opts...)
+}
+
+// newStreamer creates a Streamer connecting like the session data
+func newStreamer(data *YfData, opts ...StreamerOption) *Streamer {
+	data.mu.Lock()
+	userAgent := data.userAgent
+	data.mu.Unlock()
+
+	s := &Streamer{
+		url:       data.endpoints.Streamer,
+		origin:    data.endpoints.Root,
+		userAgent: userAgent,
+		proxy:     data.proxy,
+		policy:    reconnectBackoff{base: time.Second, max: 30 * time.Second},
+		buffer:    256,
+		symbols:   make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Symbols returns the subscribed symbols, sorted
+func (s *Streamer) Symbols() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	symbols := make([]string, 0, len(s.symbols))
+	for symbol := range s.symbols {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// Subscribe adds symbols to the stream. Symbols are upper-cased.
+func (s *Streamer) Subscribe(symbols ...string) error {
+	return s.update("subscribe", symbols, true)
+}
+
+// Unsubscribe removes symbols from the stream. Ticks of unsubscribed
+// symbols still in flight are dropped.
+func (s *Streamer) Unsubscribe(symbols ...string) error {
+	return s.update("unsubscribe", symbols, false)
+}
+
+// update records the subscription change and, when connected, sends it to
+// the streamer
+func (s *Streamer) update(action string, symbols []string, subscribed bool) error {
+	symbols = normalizeSymbols(symbols)
+	if len(symbols) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	for _, symbol := range symbols {
+		if subscribed {
+			s.symbols[symbol] = true
+		} else {
+			delete(s.symbols, symbol)
+		}
+	}
+	conn := s.conn
+	s.mu.Unlock()
+	if conn == nil {
+		return nil
+	}
+	// A failed send drops the connection; the next one subscribes again.
+	// Ticks of symbols unsubscribed meanwhile are dropped on receipt.
+	return websocket.JSON.Send(conn, map[string][]string{action: symbols})
+}
+
+// Stream connects to the streamer and returns a channel of ticks for the
+// subscribed symbols. The channel is closed once ctx is done. Stream may
+// only be called once at a time; other calls return a closed channel.
+func (s *Streamer) Stream(ctx context.Context) <-chan Tick {
+	ticks := make(chan Tick, max(s.buffer, 0))
+
+	s.mu.Lock()
+	if s.streaming {
+		s.mu.Unlock()
+		close(ticks)
+		return ticks
+	}
+	s.streaming = true
+	s.mu.Unlock()
+
+	go func() {
+		defer func() {
+			s.mu.Lock()
+			s.streaming = false
+			s.mu.Unlock()
+			close(ticks)
+		}()
+		s.run(ctx, ticks)
+	}()
+	return ticks
+}
+
+// run connects and reads until ctx is done, reconnecting as the policy
+// allows
+func (s *Streamer) run(ctx context.Context, ticks chan<- Tick) {
+	attempt := 0
+	var failedSince time.Time
+	for ctx.Err() == nil {
+		err := s.connect(ctx, ticks, func() { attempt = 0 })
+		if ctx.Err() != nil {
+			return
+		}
+		s.reportError(err)
+
+		if attempt == 0 {
+			failedSince = time.Now()
+		}
+		delay, ok := s.policy.Backoff(RetryAttempt{
+			Attempt: attempt,
+			Elapsed: time.Since(failedSince),
+			Err:     err,
+		})
+		if !ok {
+			return
+		}
+		attempt++
+		if sleepContext(ctx, delay) != nil {
+			return
+		}
+	}
+}
+
+// connect opens one connection, subscribes to every symbol and reads ticks
+// until the connection fails or ctx is done. connected is called once the
+// subscription is sent.
+func (s *Streamer) connect(ctx context.Context, ticks chan<- Tick, connected func()) error {
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	s.mu.Lock()
+	s.conn = conn
+	symbols := make([]string, 0, len(s.symbols))
+	for symbol := range s.symbols {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		if s.conn == conn {
+			s.conn = nil
+		}
+		s.mu.Unlock()
+	}()
+	if len(symbols) > 0 {
+		if err := websocket.JSON.Send(conn, map[string][]string{"subscribe": symbols}); err != nil {
+			return err
+		}
+	}
+	connected()
+
+	for {
+		var frame string
+		if err := websocket.Message.Receive(conn, &frame); err != nil {
+			return err
+		}
+
+		tick, err := parseStreamerMessage(frame)
+		if err != nil {
+			s.reportError(err)
+			continue
+		}
+		if !s.subscribed(tick.Symbol) {
+			continue
+		}
+
+		select {
+		case ticks <- tick:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// dial opens a WebSocket connection to the streamer, through the proxy if
+// one is set
+func (s *Streamer) dial(ctx context.Context) (*websocket.Conn, error) {
+	config, err := websocket.NewConfig(s.url, s.origin)
+	if err != nil {
+		return nil, err
+	}
+	config.Header.Set("User-Agent", s.userAgent)
+	if s.proxy == "" {
+		return config.DialContext(ctx)
+	}
+
+	proxyURL, err := url.Parse(s.proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy: %w", err)
+	}
+	host := config.Location.Host
+	if config.Location.Port() == "" {
+		port := "80"
+		if config.Location.Scheme == "wss" {
+			port = "443"
+		}
+		host = net.JoinHostPort(config.Location.Hostname(), port)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", proxyURL.Host)
+	if err != nil {
+		return nil, fmt.Errorf("proxy dial error: %w", err)
+	}
+	// The handshakes below do not watch ctx themselves
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	connectReq := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: host},
+		Host:   host,
+		Header: make(http.Header),
+	}
+	if user := proxyURL.User; user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		connectReq.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := connectReq.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxy connect error: %w", err)
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), connectReq)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxy connect error: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy connect error: %s", resp.Status)
+	}
+
+	var rwc net.Conn = conn
+	if config.Location.Scheme == "wss" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: config.Location.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("TLS handshake error: %w", err)
+		}
+		rwc = tlsConn
+	}
+
+	ws, err := websocket.NewClient(config, rwc)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return ws, nil
+}
+
+// subscribed reports whether symbol is subscribed
+func (s *Streamer) subscribed(symbol string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.symbols[strings.ToUpper(symbol)]
+}
+
+// reportError passes err to the error handler, if any
+func (s *Streamer) reportError(err error) {
+	if err != nil && s.onError != nil {
+		s.onError(err)
+	}
+}
+
+// reconnectBackoff is the default reconnection policy of Streamer: it never
+// gives up, doubling the delay from base up to max
+type reconnectBackoff struct {
+	base, max time.Duration
+}
+
+// Backoff implements RetryPolicy
+func (p reconnectBackoff) Backoff(a RetryAttempt) (time.Duration, bool) {
+	delay := p.base
+	for i := 0; i < a.Attempt && delay < p.max; i++ {
+		delay *= 2
+	}
+	return min(delay, p.max), true
+}
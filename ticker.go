@@ -2,8 +2,12 @@ package yfinance
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"math"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -12,6 +16,7 @@ type Ticker struct {
 	Symbol string
 	data   *YfData
 	tz     string
+	tzMu   sync.Mutex
 }
 
 // NewTicker creates a new Ticker instance
@@ -71,31 +76,81 @@ func (t *Ticker) History(ctx context.Context, options *HistoryOptions) (*History
 	}
 
 	// Get timezone if needed
-	if t.tz == "" {
-		tz, err := t.GetTimezone(ctx)
-		if err == nil {
-			t.tz = tz
+	t.GetTimezone(ctx)
+
+	// Intraday ranges longer than Yahoo serves per request are fetched in
+	// chunks
+	chunks, err := historyChunks(t.Symbol, options, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	var hr *HistoryResult
+	var meta chartMeta
+	if len(chunks) > 0 {
+		hr, meta, err = t.fetchChunks(ctx, options, chunks)
+	} else {
+		hr, meta, err = t.fetchChart(ctx, options)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	t.postProcess(ctx, hr, meta, options)
+	return hr, nil
+}
+
+// postProcess repairs, adjusts and rounds fetched bars as options request
+func (t *Ticker) postProcess(ctx context.Context, hr *HistoryResult, meta chartMeta, options *HistoryOptions) {
+	if options.Repair {
+		t.repair(ctx, hr, options)
+	}
+
+	// Adjust prices if requested; AutoAdjust takes precedence
+	switch {
+	case options.AutoAdjust:
+		hr.AutoAdjustPrices()
+	case options.BackAdjust:
+		hr.BackAdjustPrices()
+	}
+
+	// Round last so that adjusted prices are rounded too
+	if options.Rounding {
+		decimals := 2
+		switch {
+		case options.Precision != nil:
+			decimals = *options.Precision
+		case meta.PriceHint != nil:
+			decimals = *meta.PriceHint
 		}
+		hr.Round(decimals)
 	}
+}
 
+// fetchChart fetches and parses a single chart request
+func (t *Ticker) fetchChart(ctx context.Context, options *HistoryOptions) (*HistoryResult, chartMeta, error) {
 	params := options.ToParams()
-	endpoint := fmt.Sprintf("%s/v8/finance/chart/%s", BaseURL, t.Symbol)
+	endpoint := fmt.Sprintf("%s/v8/finance/chart/%s", t.data.endpoints.Query2, t.Symbol)
 
 	var result chartResponse
 	if err := t.data.GetRawJSON(ctx, endpoint, params, &result); err != nil {
-		return nil, err
+		return nil, chartMeta{}, err
 	}
 
 	if result.Chart.Error != nil {
-		return nil, fmt.Errorf("chart error: %s", result.Chart.Error.Description)
+		return nil, chartMeta{}, fmt.Errorf("chart error: %s", result.Chart.Error.Description)
 	}
 
 	if len(result.Chart.Result) == 0 {
-		return nil, NewYFPricesMissingError(t.Symbol, "")
+		return nil, chartMeta{}, NewYFPricesMissingError(t.Symbol, "")
 	}
 
 	chartResult := result.Chart.Result[0]
-	return t.parseChartResult(chartResult, options)
+	hr, err := t.parseChartResult(chartResult, options)
+	if err != nil {
+		return nil, chartMeta{}, err
+	}
+	return hr, chartResult.Meta, nil
 }
 
 // HistoryOptions defines options for fetching historical data
@@ -105,11 +160,12 @@ type HistoryOptions struct {
 	Start       *time.Time // Start date
 	End         *time.Time // End date
 	PrePost     bool       // Include pre/post market data
-	AutoAdjust  bool       // Auto-adjust prices for splits/dividends
-	BackAdjust  bool       // Back-adjust prices
+	AutoAdjust  bool       // Adjust OHLC for splits and dividends
+	BackAdjust  bool       // Restore as-traded prices and volumes (ignored with AutoAdjust)
 	Repair      bool       // Detect and repair price errors
-	KeepNaN     bool       // Keep NaN rows
-	Rounding    bool       // Round to 2 decimal places
+	KeepNaN     bool       // Keep rows for which every field is missing
+	Rounding    bool       // Round prices to the instrument's precision (priceHint, else 2 decimals)
+	Precision   *int       // Decimal places used by Rounding instead of priceHint
 	Timeout     int        // Request timeout in seconds
 	ShowErrors  bool       // Show errors in response
 }
@@ -185,22 +241,27 @@ func (o *HistoryOptions) ToParams() map[string]string {
 		params["includePrePost"] = "true"
 	}
 
-	if o.ShowErrors {
-		params["events"] = "div,split"
-	}
+	params["events"] = "div,splits,capitalGains"
 
 	return params
 }
 
 // HistoryResult contains historical price data
 type HistoryResult struct {
-	Meta       HistoryMeta
-	Data       []PriceData
-	Dividends  []DividendData
-	Splits     []SplitData
-	Timezone   string
-	Currency   string
-	Exchange   string
+	Meta         HistoryMeta
+	Data         []PriceData
+	Dividends    []DividendData
+	Splits       []SplitData
+	CapitalGains []CapitalGainData
+	Timezone     string
+	Currency     string
+	Exchange     string
+
+	// AutoAdjusted is set once OHLC are adjusted for dividends and splits
+	AutoAdjusted bool
+	// BackAdjusted is set once prices and volumes are as traded. Yahoo
+	// reports them adjusted for splits (but not dividends) by default.
+	BackAdjusted bool
 }
 
 // HistoryMeta contains metadata about the historical data
@@ -217,9 +278,19 @@ type HistoryMeta struct {
 	RegularMarketPrice float64
 	ChartPreviousClose float64
 	PreviousClose      float64
+	PriceHint          int // Decimal places Yahoo quotes the instrument with (0 if not reported)
+
+	// CurrentTradingPeriod holds the sessions of the latest trading day
+	CurrentTradingPeriod TradingDay
+	// TradingPeriods holds the sessions of every day of intraday data. Pre
+	// and post-market sessions are only reported with PrePost.
+	TradingPeriods []TradingDay
 }
 
-// PriceData represents a single price data point
+// PriceData represents a single price data point.
+// Prices Yahoo did not report are NaN; Missing records every field that was
+// not reported, including Volume, which is 0 when missing. Fields rebuilt
+// by HistoryOptions.Repair are no longer marked missing.
 type PriceData struct {
 	Date          time.Time
 	Open          float64
@@ -228,6 +299,68 @@ type PriceData struct {
 	Close         float64
 	AdjClose      float64
 	Volume        int64
+	Missing       PriceField
+	Repaired      RepairReason // Why HistoryOptions.Repair changed the bar, if it did
+	Session       Session      // Trading session of the bar, tagged when HistoryOptions.PrePost is set
+}
+
+// PriceField identifies the fields of a PriceData
+type PriceField uint8
+
+const (
+	FieldOpen PriceField = 1 << iota
+	FieldHigh
+	FieldLow
+	FieldClose
+	FieldAdjClose
+	FieldVolume
+
+	// FieldOHLC covers the open, high, low and close prices
+	FieldOHLC = FieldOpen | FieldHigh | FieldLow | FieldClose
+	// FieldAll covers every field of a PriceData
+	FieldAll = FieldOHLC | FieldAdjClose | FieldVolume
+)
+
+// String returns the names of the fields, separated by "|"
+func (f PriceField) String() string {
+	if f == 0 {
+		return "none"
+	}
+	var names []string
+	for _, field := range []struct {
+		flag PriceField
+		name string
+	}{
+		{FieldOpen, "Open"},
+		{FieldHigh, "High"},
+		{FieldLow, "Low"},
+		{FieldClose, "Close"},
+		{FieldAdjClose, "AdjClose"},
+		{FieldVolume, "Volume"},
+	} {
+		if f&field.flag != 0 {
+			names = append(names, field.name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// IsMissing reports whether any of fields was not reported by Yahoo
+func (p PriceData) IsMissing(fields PriceField) bool {
+	return p.Missing&fields != 0
+}
+
+// missingIf returns field if v is NaN
+func missingIf(field PriceField, v float64) PriceField {
+	if math.IsNaN(v) {
+		return field
+	}
+	return 0
+}
+
+// IsEmpty reports whether every field of the bar is missing
+func (p PriceData) IsEmpty() bool {
+	return p.Missing&FieldAll == FieldAll
 }
 
 // DividendData represents dividend information
@@ -236,6 +369,13 @@ type DividendData struct {
 	Amount float64
 }
 
+// CapitalGainData represents a capital gain distribution, paid by mutual
+// funds and ETFs
+type CapitalGainData struct {
+	Date   time.Time
+	Amount float64
+}
+
 // SplitData represents stock split information
 type SplitData struct {
 	Date     time.Time
@@ -277,26 +417,59 @@ type chartMeta struct {
 	RegularMarketPrice   float64 `json:"regularMarketPrice"`
 	ChartPreviousClose   float64 `json:"chartPreviousClose"`
 	PreviousClose        float64 `json:"previousClose,omitempty"`
+	PriceHint            *int    `json:"priceHint,omitempty"`
+
+	CurrentTradingPeriod *chartCurrentTradingPeriod `json:"currentTradingPeriod,omitempty"`
+	TradingPeriods       *chartTradingPeriods       `json:"tradingPeriods,omitempty"`
 }
 
 // chartIndicators contains price indicators
 type chartIndicators struct {
 	Quote []chartQuote `json:"quote"`
 	Adjclose []struct {
-		Adjclose []float64 `json:"adjclose"`
+		Adjclose nullFloats `json:"adjclose"`
 	} `json:"adjclose,omitempty"`
 }
 
 // chartQuote contains quote data
 type chartQuote struct {
-	Open   []float64 `json:"open"`
-	High   []float64 `json:"high"`
-	Low    []float64 `json:"low"`
-	Close  []float64 `json:"close"`
-	Volume []int64   `json:"volume"`
+	Open   nullFloats `json:"open"`
+	High   nullFloats `json:"high"`
+	Low    nullFloats `json:"low"`
+	Close  nullFloats `json:"close"`
+	Volume nullFloats `json:"volume"`
 }
 
-// chartEvents contains event data (dividends, splits)
+// nullFloats is a chart column in which null entries decode to NaN
+type nullFloats []float64
+
+// UnmarshalJSON implements json.Unmarshaler
+func (n *nullFloats) UnmarshalJSON(data []byte) error {
+	var values []*float64
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	*n = make(nullFloats, len(values))
+	for i, v := range values {
+		if v == nil {
+			(*n)[i] = math.NaN()
+		} else {
+			(*n)[i] = *v
+		}
+	}
+	return nil
+}
+
+// at returns the value at i, or NaN if it is null or out of range
+func (n nullFloats) at(i int) float64 {
+	if i < 0 || i >= len(n) {
+		return math.NaN()
+	}
+	return n[i]
+}
+
+// chartEvents contains event data (dividends, splits, capital gains),
+// keyed by timestamp
 type chartEvents struct {
 	Dividends map[string]struct {
 		Amount float64 `json:"amount"`
@@ -305,6 +478,9 @@ type chartEvents struct {
 		Numerator   float64 `json:"numerator"`
 		Denominator float64 `json:"denominator"`
 	} `json:"splits"`
+	CapitalGains map[string]struct {
+		Amount float64 `json:"amount"`
+	} `json:"capitalGains"`
 }
 
 // parseChartResult parses the chart result into HistoryResult
@@ -328,6 +504,9 @@ func (t *Ticker) parseChartResult(result chartResult, options *HistoryOptions) (
 		ChartPreviousClose: result.Meta.ChartPreviousClose,
 		PreviousClose:      result.Meta.PreviousClose,
 	}
+	if result.Meta.PriceHint != nil {
+		hr.Meta.PriceHint = *result.Meta.PriceHint
+	}
 
 	if result.Meta.FirstTradeDate > 0 {
 		hr.Meta.FirstTradeDate = time.Unix(result.Meta.FirstTradeDate, 0)
@@ -344,35 +523,59 @@ func (t *Ticker) parseChartResult(result chartResult, options *HistoryOptions) (
 		}
 	}
 
-	// Parse price data
-	if len(result.Timestamp) > 0 && len(result.Indicators.Quote) > 0 {
-		quote := result.Indicators.Quote[0]
-		adjClose := []float64(nil)
-		if len(result.Indicators.Adjclose) > 0 {
+	if current := result.Meta.CurrentTradingPeriod; current != nil {
+		hr.Meta.CurrentTradingPeriod = TradingDay{
+			Pre:     current.Pre.period(loc),
+			Regular: current.Regular.period(loc),
+			Post:    current.Post.period(loc),
+		}
+	}
+	if result.Meta.TradingPeriods != nil {
+		hr.Meta.TradingPeriods = result.Meta.TradingPeriods.days(loc)
+	}
+
+	// Parse price data. Columns may be shorter than the timestamps or hold
+	// nulls; both are treated as missing values.
+	if len(result.Timestamp) > 0 {
+		var quote chartQuote
+		if len(result.Indicators.Quote) > 0 {
+			quote = result.Indicators.Quote[0]
+		}
+		var adjClose nullFloats
+		hasAdjClose := len(result.Indicators.Adjclose) > 0
+		if hasAdjClose {
 			adjClose = result.Indicators.Adjclose[0].Adjclose
 		}
 
 		hr.Data = make([]PriceData, 0, len(result.Timestamp))
 		for i, ts := range result.Timestamp {
-			if i >= len(quote.Open) {
-				break
-			}
-
 			pd := PriceData{
-				Date:   time.Unix(ts, 0).In(loc),
-				Open:   quote.Open[i],
-				High:   quote.High[i],
-				Low:    quote.Low[i],
-				Close:  quote.Close[i],
-				Volume: quote.Volume[i],
+				Date:  time.Unix(ts, 0).In(loc),
+				Open:  quote.Open.at(i),
+				High:  quote.High.at(i),
+				Low:   quote.Low.at(i),
+				Close: quote.Close.at(i),
 			}
 
-			if adjClose != nil && i < len(adjClose) {
-				pd.AdjClose = adjClose[i]
+			if hasAdjClose {
+				pd.AdjClose = adjClose.at(i)
 			} else {
 				pd.AdjClose = pd.Close
 			}
 
+			if volume := quote.Volume.at(i); math.IsNaN(volume) {
+				pd.Missing |= FieldVolume
+			} else {
+				pd.Volume = int64(volume)
+			}
+			pd.Missing |= missingIf(FieldOpen, pd.Open) | missingIf(FieldHigh, pd.High) |
+				missingIf(FieldLow, pd.Low) | missingIf(FieldClose, pd.Close) |
+				missingIf(FieldAdjClose, pd.AdjClose)
+
+			if pd.IsEmpty() && !options.KeepNaN {
+				continue
+			}
+
 			hr.Data = append(hr.Data, pd)
 		}
 	}
@@ -405,46 +608,63 @@ func (t *Ticker) parseChartResult(result chartResult, options *HistoryOptions) (
 		}
 	}
 
-	// Auto-adjust prices if requested
-	if options.AutoAdjust && len(hr.Data) > 0 {
-		hr.AutoAdjustPrices()
-	}
-
-	return hr, nil
-}
-
-// AutoAdjustPrices adjusts historical prices for splits and dividends
-func (hr *HistoryResult) AutoAdjustPrices() {
-	if len(hr.Data) == 0 {
-		return
+	// Parse capital gains
+	if result.Events != nil && result.Events.CapitalGains != nil {
+		hr.CapitalGains = make([]CapitalGainData, 0, len(result.Events.CapitalGains))
+		for tsStr, gain := range result.Events.CapitalGains {
+			var ts int64
+			fmt.Sscanf(tsStr, "%d", &ts)
+			hr.CapitalGains = append(hr.CapitalGains, CapitalGainData{
+				Date:   time.Unix(ts, 0).In(loc),
+				Amount: gain.Amount,
+			})
+		}
 	}
 
-	// Get the last close and adjClose
-	lastClose := hr.Data[len(hr.Data)-1].Close
-	lastAdjClose := hr.Data[len(hr.Data)-1].AdjClose
+	// Events are keyed by timestamp, so map order is random
+	hr.sortEvents()
 
-	if lastClose == 0 || lastAdjClose == 0 {
-		return
+	// Daily and longer bars only cover the regular session
+	if options.PrePost {
+		if IntradayIntervals[options.Interval] {
+			tagSessions(hr.Data, hr.Meta)
+		} else {
+			for i := range hr.Data {
+				hr.Data[i].Session = SessionRegular
+			}
+		}
 	}
 
-	adjustFactor := lastAdjClose / lastClose
+	return hr, nil
+}
 
-	for i := range hr.Data {
-		hr.Data[i].AdjClose = hr.Data[i].Close * adjustFactor
-	}
+// sortEvents sorts dividends, splits and capital gains by date
+func (hr *HistoryResult) sortEvents() {
+	sort.Slice(hr.Dividends, func(i, j int) bool {
+		return hr.Dividends[i].Date.Before(hr.Dividends[j].Date)
+	})
+	sort.Slice(hr.Splits, func(i, j int) bool {
+		return hr.Splits[i].Date.Before(hr.Splits[j].Date)
+	})
+	sort.Slice(hr.CapitalGains, func(i, j int) bool {
+		return hr.CapitalGains[i].Date.Before(hr.CapitalGains[j].Date)
+	})
 }
 
 // GetTimezone fetches the timezone for the ticker
 func (t *Ticker) GetTimezone(ctx context.Context) (string, error) {
-	if t.tz != "" {
-		return t.tz, nil
+	t.tzMu.Lock()
+	tz := t.tz
+	t.tzMu.Unlock()
+	if tz != "" {
+		return tz, nil
 	}
 
 	params := map[string]string{
 		"range":    "1d",
 		"interval": "1d",
 	}
-	endpoint := fmt.Sprintf("%s/v8/finance/chart/%s", BaseURL, t.Symbol)
+	endpoint := fmt.Sprintf("%s/v8/finance/chart/%s", t.data.endpoints.Query2, t.Symbol)
 
 	var result chartResponse
 	if err := t.data.GetRawJSON(ctx, endpoint, params, &result); err != nil {
@@ -455,7 +675,9 @@ func (t *Ticker) GetTimezone(ctx context.Context) (string, error) {
 		return "", NewYFTzMissingError(t.Symbol)
 	}
 
-	tz := result.Chart.Result[0].Meta.Timezone
+	tz = result.Chart.Result[0].Meta.Timezone
+	t.tzMu.Lock()
 	t.tz = tz
+	t.tzMu.Unlock()
 	return tz, nil
 }
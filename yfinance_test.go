@@ -1,9 +1,28 @@
 package yfinance
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
+
+	"golang.org/x/net/websocket"
 )
 
 func TestNewTicker(t *testing.T) {
@@ -103,6 +122,9 @@ func TestHistoryOptionsToParams(t *testing.T) {
 	if params["includePrePost"] != "true" {
 		t.Errorf("Expected includePrePost true, got %s", params["includePrePost"])
 	}
+	if params["events"] != "div,splits,capitalGains" {
+		t.Errorf("Expected all events to be requested, got %s", params["events"])
+	}
 
 	// Test with start/end dates
 	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
@@ -194,6 +216,2211 @@ func TestMICMapping(t *testing.T) {
 	}
 }
 
+func TestNewClientOptions(t *testing.T) {
+	endpoints := Endpoints{
+		Query1: "http://127.0.0.1:1",
+		Query2: "http://127.0.0.1:2",
+		Root:   "http://127.0.0.1:3",
+	}
+	client := NewClient(
+		WithRetries(5),
+		WithTimeout(5*time.Second),
+		WithUserAgent("yfinance-go-test"),
+		WithCacheDir(""),
+		WithEndpoints(endpoints),
+	)
+
+	data := client.Data()
+	if policy, ok := data.retryPolicy.(*ExponentialBackoff); !ok || policy.MaxRetries != 5 {
+		t.Errorf("Expected 5 retries, got %+v", data.retryPolicy)
+	}
+	if data.userAgent != "yfinance-go-test" {
+		t.Errorf("Expected custom user agent, got %s", data.userAgent)
+	}
+	if data.endpoints.Query2 != endpoints.Query2 {
+		t.Errorf("Expected Query2 %s, got %s", endpoints.Query2, data.endpoints.Query2)
+	}
+	if data.endpoints.Cookie != CookieURL {
+		t.Errorf("Expected unset Cookie endpoint to default to %s, got %s", CookieURL, data.endpoints.Cookie)
+	}
+	if data.cacheDir != "" {
+		t.Errorf("Expected cache to be disabled, got %s", data.cacheDir)
+	}
+}
+
+func TestClientSharesSession(t *testing.T) {
+	client := NewClient(WithCacheDir(""))
+
+	if client.Ticker("AAPL").data != client.Data() {
+		t.Error("Expected Ticker to share the client session")
+	}
+	if client.Tickers([]string{"AAPL"}).data != client.Data() {
+		t.Error("Expected Tickers to share the client session")
+	}
+	if client.Search("Apple").data != client.Data() {
+		t.Error("Expected Search to share the client session")
+	}
+	if client.Lookup("Apple").data != client.Data() {
+		t.Error("Expected Lookup to share the client session")
+	}
+	if NewClient(WithCacheDir("")).Data() == client.Data() {
+		t.Error("Expected separate clients to have separate sessions")
+	}
+}
+
+func TestCookieCachePerSession(t *testing.T) {
+	dir := t.TempDir()
+	save := func(data *YfData) {
+		data.mu.Lock()
+		defer data.mu.Unlock()
+		data.cookie, data.crumb = "cookie", "crumb-"+data.userAgent
+		if err := data.saveCookieCache(); err != nil {
+			t.Fatalf("Failed to save the cookie cache: %v", err)
+		}
+	}
+
+	save(NewClient(WithCacheDir(dir), WithProxy(""), WithUserAgent("agent-a")).Data())
+	if data := NewClient(WithCacheDir(dir), WithProxy(""), WithUserAgent("agent-a")).Data(); data.crumb != "crumb-agent-a" {
+		t.Errorf("Expected the same settings to share the cached crumb, got %q", data.crumb)
+	}
+	if data := NewClient(WithCacheDir(dir), WithProxy(""), WithUserAgent("agent-b")).Data(); data.crumb != "" {
+		t.Errorf("Expected another user agent not to load the cached crumb, got %q", data.crumb)
+	}
+	if data := NewClient(WithCacheDir(dir), WithProxy("http://proxy:8080"), WithUserAgent("agent-a")).Data(); data.crumb != "" {
+		t.Errorf("Expected another proxy not to load the cached crumb, got %q", data.crumb)
+	}
+
+	// Random user agents take over the one the cached crumb was issued to
+	first := NewClient(WithCacheDir(dir), WithProxy("")).Data()
+	save(first)
+	if data := NewClient(WithCacheDir(dir), WithProxy("")).Data(); data.crumb != first.crumb || data.userAgent != first.userAgent {
+		t.Errorf("Expected %q with %q, got %q with %q", first.crumb, first.userAgent, data.crumb, data.userAgent)
+	}
+}
+
+// handlerDoer serves requests in-process with h, recording every URL seen
+type handlerDoer struct {
+	h    http.Handler
+	mu   sync.Mutex
+	urls []string
+}
+
+func (d *handlerDoer) Do(req *http.Request) (*http.Response, error) {
+	d.mu.Lock()
+	d.urls = append(d.urls, req.URL.String())
+	d.mu.Unlock()
+
+	rec := httptest.NewRecorder()
+	d.h.ServeHTTP(rec, req)
+	resp := rec.Result()
+	resp.Request = req
+	return resp, nil
+}
+
+func (d *handlerDoer) seen(substr string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, u := range d.urls {
+		if strings.Contains(u, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// fakeYahooHandler answers crumb and chart requests with canned data
+func fakeYahooHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/test/getcrumb", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("testcrumb"))
+	})
+	mux.HandleFunc("/v8/finance/chart/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"chart":{"result":[{"meta":{"symbol":"AAPL","timezone":"EST"}}],"error":null}}`))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {})
+	return mux
+}
+
+func TestWithHTTPClientRoutesAllRequests(t *testing.T) {
+	doer := &handlerDoer{h: fakeYahooHandler()}
+	client := NewClient(WithHTTPClient(doer), WithCacheDir(""))
+
+	tz, err := client.Ticker("AAPL").GetTimezone(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tz != "EST" {
+		t.Errorf("Expected timezone EST, got %s", tz)
+	}
+	if !doer.seen("fc.yahoo.com") {
+		t.Error("Expected cookie bootstrap to go through the injected Doer")
+	}
+	if !doer.seen("/v1/test/getcrumb") {
+		t.Error("Expected crumb request to go through the injected Doer")
+	}
+	if !doer.seen("crumb=testcrumb") {
+		t.Error("Expected chart request to carry the crumb")
+	}
+}
+
+func TestBareDoerFunc(t *testing.T) {
+	// Unlike handlerDoer, a bare DoerFunc does not set Response.Request
+	h := fakeYahooHandler()
+	doer := DoerFunc(func(req *http.Request) (*http.Response, error) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		resp := rec.Result()
+		resp.Request = nil
+		return resp, nil
+	})
+	client := NewClient(WithHTTPClient(doer), WithCacheDir(""))
+
+	tz, err := client.Ticker("AAPL").GetTimezone(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tz != "EST" {
+		t.Errorf("Expected timezone EST, got %s", tz)
+	}
+}
+
+func TestWithMiddlewareOrder(t *testing.T) {
+	var order []string
+	tag := func(name string) Middleware {
+		return func(next Doer) Doer {
+			return DoerFunc(func(req *http.Request) (*http.Response, error) {
+				order = append(order, name)
+				return next.Do(req)
+			})
+		}
+	}
+
+	doer := &handlerDoer{h: fakeYahooHandler()}
+	client := NewClient(
+		WithHTTPClient(doer),
+		WithCacheDir(""),
+		WithMiddleware(tag("outer"), tag("inner")),
+	)
+
+	if _, err := client.Ticker("AAPL").GetTimezone(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(order) < 2 || order[0] != "outer" || order[1] != "inner" {
+		t.Errorf("Expected outer middleware to run first, got %v", order)
+	}
+}
+
+func TestLocalEndpointsConsentFlow(t *testing.T) {
+	var collected bool
+	mux := http.NewServeMux()
+	mux.HandleFunc("/fc", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/guce/consent", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<form><input type="hidden" name="csrfToken" value="token123"></form>`))
+	})
+	mux.HandleFunc("/consent/v2/collectConsent", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("csrfToken") == "token123" {
+			collected = true
+			http.SetCookie(w, &http.Cookie{Name: "consent", Value: "yes", Path: "/"})
+		}
+	})
+	mux.HandleFunc("/guce/copyConsent", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/v1/test/getcrumb", func(w http.ResponseWriter, r *http.Request) {
+		// Only hand out a crumb once consent was given
+		if _, err := r.Cookie("consent"); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("localcrumb"))
+	})
+	mux.HandleFunc("/v8/finance/chart/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("crumb") != "localcrumb" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"chart":{"result":[{"meta":{"symbol":"AAPL","timezone":"EST"}}],"error":null}}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := NewClient(
+		WithHTTPClient(server.Client()),
+		WithEndpoints(LocalEndpoints(server.URL)),
+		WithCacheDir(""),
+	)
+
+	tz, err := client.Ticker("AAPL").GetTimezone(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tz != "EST" {
+		t.Errorf("Expected timezone EST, got %s", tz)
+	}
+	if !collected {
+		t.Error("Expected consent to be collected from the local server")
+	}
+}
+
+func TestIsConsentURL(t *testing.T) {
+	yd := &YfData{endpoints: LocalEndpoints("http://127.0.0.1:8080")}
+
+	tests := []struct {
+		url      string
+		expected bool
+	}{
+		{"https://consent.yahoo.com/v2/collectConsent", true},
+		{"http://127.0.0.1:8080/guce/consent", true},
+		{"http://127.0.0.1:8080/consent/v2/collectConsent", true},
+		{"http://127.0.0.1:8080/v8/finance/chart/AAPL", false},
+		{"http://127.0.0.1:8080/gucefoo", false},
+	}
+
+	for _, tt := range tests {
+		if got := yd.isConsentURL(tt.url); got != tt.expected {
+			t.Errorf("isConsentURL(%s) = %v, expected %v", tt.url, got, tt.expected)
+		}
+	}
+}
+
+func TestCassetteBinaryBody(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	payload := []byte{0x1f, 0x8b, 0x08, 0x00, 0xff, 0xfe, 'y', 'f'}
+
+	recorder, err := NewCassette(path, CassetteRecord)
+	if err != nil {
+		t.Fatalf("Failed to create cassette: %v", err)
+	}
+	for _, body := range [][]byte{payload, []byte(`{"ok":true}`)} {
+		resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(bytes.NewReader(body))}
+		if _, err := recorder.record(http.MethodGet, "https://query1.finance.yahoo.com/raw", map[string]string{"n": fmt.Sprint(len(body))}, resp); err != nil {
+			t.Fatalf("Failed to record: %v", err)
+		}
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("Failed to save cassette: %v", err)
+	}
+	if recorder.Interactions[0].BodyEncoding != "base64" || recorder.Interactions[1].BodyEncoding != "" {
+		t.Errorf("Expected only the binary body to be base64-encoded, got %+v", recorder.Interactions)
+	}
+
+	player, err := NewCassette(path, CassetteReplay)
+	if err != nil {
+		t.Fatalf("Failed to load cassette: %v", err)
+	}
+	for _, body := range [][]byte{payload, []byte(`{"ok":true}`)} {
+		resp, err := player.replay(http.MethodGet, "http://127.0.0.1:1/raw", map[string]string{"n": fmt.Sprint(len(body))})
+		if err != nil {
+			t.Fatalf("Failed to replay: %v", err)
+		}
+		got, _ := io.ReadAll(resp.Body)
+		if !bytes.Equal(got, body) || resp.ContentLength != int64(len(body)) {
+			t.Errorf("Expected body %q, got %q", body, got)
+		}
+	}
+}
+
+func TestCassetteRecordReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	ctx := context.Background()
+
+	recorder, err := NewCassette(path, CassetteRecord)
+	if err != nil {
+		t.Fatalf("Failed to create cassette: %v", err)
+	}
+	live := NewClient(
+		WithHTTPClient(&handlerDoer{h: fakeYahooHandler()}),
+		WithCacheDir(""),
+		WithCassette(recorder),
+	)
+	if _, err := live.Ticker("AAPL").GetTimezone(ctx); err != nil {
+		t.Fatalf("Unexpected error while recording: %v", err)
+	}
+	if len(recorder.Interactions) != 1 {
+		t.Fatalf("Expected 1 recorded interaction, got %d", len(recorder.Interactions))
+	}
+	if _, ok := recorder.Interactions[0].Params["crumb"]; ok {
+		t.Error("Expected the crumb to be stripped from the cassette")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Expected interactions to be buffered until Close")
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("Failed to save cassette: %v", err)
+	}
+
+	player, err := NewCassette(path, CassetteReplay)
+	if err != nil {
+		t.Fatalf("Failed to load cassette: %v", err)
+	}
+	offline := NewClient(
+		WithHTTPClient(DoerFunc(func(req *http.Request) (*http.Response, error) {
+			t.Errorf("Unexpected network request in replay mode: %s", req.URL)
+			return nil, errors.New("network disabled")
+		})),
+		WithCacheDir(""),
+		WithEndpoints(LocalEndpoints("http://127.0.0.1:1")),
+		WithCassette(player),
+	)
+
+	tz, err := offline.Ticker("AAPL").GetTimezone(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error while replaying: %v", err)
+	}
+	if tz != "EST" {
+		t.Errorf("Expected timezone EST, got %s", tz)
+	}
+
+	_, err = offline.Ticker("MSFT").GetTimezone(ctx)
+	var miss *YFCassetteMissError
+	if !errors.As(err, &miss) {
+		t.Errorf("Expected YFCassetteMissError for an unrecorded request, got %v", err)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		{"", 0, false},
+		{"7", 7 * time.Second, true},
+		{"-1", 0, false},
+		{"Fri, 10 May 2024 12:00:30 GMT", 30 * time.Second, true},
+		{"Fri, 10 May 2024 11:00:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value, now)
+		if got != tt.expected || ok != tt.ok {
+			t.Errorf("parseRetryAfter(%q) = %v, %v, expected %v, %v", tt.value, got, ok, tt.expected, tt.ok)
+		}
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	policy := &ExponentialBackoff{
+		MaxRetries: 3,
+		BaseDelay:  time.Second,
+		MaxDelay:   4 * time.Second,
+		MaxElapsed: 10 * time.Second,
+		Statuses:   map[int]bool{http.StatusTooManyRequests: true},
+	}
+	rateLimited := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}}
+
+	for attempt, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		delay, ok := policy.Backoff(RetryAttempt{Attempt: attempt, Response: rateLimited})
+		if !ok || delay != expected {
+			t.Errorf("Attempt %d: expected %v, got %v (retry=%v)", attempt, expected, delay, ok)
+		}
+	}
+
+	if _, ok := policy.Backoff(RetryAttempt{Attempt: 3, Response: rateLimited}); ok {
+		t.Error("Expected no retry after MaxRetries")
+	}
+	if _, ok := policy.Backoff(RetryAttempt{Response: &http.Response{StatusCode: http.StatusNotFound}}); ok {
+		t.Error("Expected 404 not to be retried")
+	}
+	if _, ok := policy.Backoff(RetryAttempt{Attempt: 2, Elapsed: 9 * time.Second, Response: rateLimited}); ok {
+		t.Error("Expected no retry past MaxElapsed")
+	}
+	if _, ok := policy.Backoff(RetryAttempt{Err: context.Canceled}); ok {
+		t.Error("Expected cancellation not to be retried")
+	}
+
+	rateLimited.Header.Set("Retry-After", "3")
+	if delay, ok := policy.Backoff(RetryAttempt{Response: rateLimited}); !ok || delay != 3*time.Second {
+		t.Errorf("Expected Retry-After to set the delay to 3s, got %v", delay)
+	}
+}
+
+func TestRetryAbortsOnCancel(t *testing.T) {
+	doer := &handlerDoer{h: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "getcrumb") {
+			w.Write([]byte("testcrumb"))
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})}
+	policy := &ExponentialBackoff{
+		MaxRetries: 3,
+		BaseDelay:  time.Hour,
+		Statuses:   map[int]bool{http.StatusServiceUnavailable: true},
+	}
+	client := NewClient(WithHTTPClient(doer), WithCacheDir(""), WithRetryPolicy(policy))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := client.Ticker("AAPL").GetTimezone(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the retry wait to abort on cancel, took %v", elapsed)
+	}
+}
+
+func TestWithRetriesZeroDisablesRetries(t *testing.T) {
+	var attempts int
+	doer := &handlerDoer{h: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "getcrumb") {
+			w.Write([]byte("testcrumb"))
+			return
+		}
+		if strings.Contains(r.URL.Path, "/v8/finance/chart/") {
+			attempts++
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})}
+	client := NewClient(WithHTTPClient(doer), WithCacheDir(""), WithRetries(0))
+
+	if _, err := client.Ticker("AAPL").GetTimezone(context.Background()); err == nil {
+		t.Error("Expected an error")
+	}
+	if attempts != 1 {
+		t.Errorf("Expected a single attempt, got %d", attempts)
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	b := newTokenBucket(RateLimit{Rate: 10, Burst: 2})
+	now := b.last
+
+	if d := b.reserve(now); d != 0 {
+		t.Errorf("Expected the first token immediately, got %v", d)
+	}
+	if d := b.reserve(now); d != 0 {
+		t.Errorf("Expected the burst token immediately, got %v", d)
+	}
+	if d := b.reserve(now); d != 100*time.Millisecond {
+		t.Errorf("Expected to wait 100ms, got %v", d)
+	}
+	if d := b.reserve(now.Add(time.Second)); d != 0 {
+		t.Errorf("Expected the bucket to refill, got %v", d)
+	}
+}
+
+func TestRateLimiterPerHost(t *testing.T) {
+	limiter := NewRateLimiter(RateLimit{}, map[string]RateLimit{
+		"slow.example": {Rate: 1, Burst: 1},
+	})
+
+	ctx := context.Background()
+	for i := 0; i < 100; i++ {
+		if err := limiter.Wait(ctx, "fast.example"); err != nil {
+			t.Fatalf("Expected unlimited host not to wait: %v", err)
+		}
+	}
+
+	if err := limiter.Wait(ctx, "slow.example"); err != nil {
+		t.Fatalf("Expected the first request to pass: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx, "slow.example"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the second request to be throttled, got %v", err)
+	}
+}
+
+func TestRateLimiterSharedAcrossClients(t *testing.T) {
+	var mu sync.Mutex
+	times := make(map[string][]time.Time)
+	doer := &handlerDoer{h: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times[r.URL.Host] = append(times[r.URL.Host], time.Now())
+		mu.Unlock()
+		fakeYahooHandler().ServeHTTP(w, r)
+	})}
+
+	limiter := NewRateLimiter(RateLimit{Rate: 20, Burst: 1}, nil)
+	clients := []*Client{
+		NewClient(WithHTTPClient(doer), WithCacheDir(""), WithRateLimiter(limiter)),
+		NewClient(WithHTTPClient(doer), WithCacheDir(""), WithRateLimiter(limiter)),
+	}
+
+	var wg sync.WaitGroup
+	for _, client := range clients {
+		for _, symbol := range []string{"AAPL", "MSFT"} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				client.Ticker(symbol).GetTimezone(context.Background())
+			}()
+		}
+	}
+	wg.Wait()
+
+	// Requests of both clients to the same host draw from one bucket
+	for host, hostTimes := range times {
+		if len(hostTimes) < 2 {
+			continue
+		}
+		elapsed := hostTimes[len(hostTimes)-1].Sub(hostTimes[0])
+		if min := time.Duration(len(hostTimes)-1) * 50 * time.Millisecond; elapsed < min-10*time.Millisecond {
+			t.Errorf("Expected %d requests to %s to take at least %v, took %v", len(hostTimes), host, min, elapsed)
+		}
+	}
+}
+
+func TestMemoryCache(t *testing.T) {
+	cache := NewMemoryCache(2)
+
+	cache.Set("a", []byte("1"), time.Minute)
+	cache.Set("b", []byte("2"), time.Minute)
+	cache.Get("a")
+	cache.Set("c", []byte("3"), time.Minute)
+
+	if _, ok := cache.Get("b"); ok {
+		t.Error("Expected the least recently used entry to be evicted")
+	}
+	if v, ok := cache.Get("a"); !ok || string(v) != "1" {
+		t.Errorf("Expected a=1, got %q", v)
+	}
+
+	cache.Set("d", []byte("4"), -time.Second)
+	if _, ok := cache.Get("d"); ok {
+		t.Error("Expected expired entry to be missing")
+	}
+}
+
+func TestDiskCache(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewDiskCache(dir)
+	if err != nil {
+		t.Fatalf("Failed to create disk cache: %v", err)
+	}
+
+	cache.Set("key", []byte(`{"ok":true}`), time.Minute)
+	cache.Set("expired", []byte("x"), -time.Second)
+
+	reopened, _ := NewDiskCache(dir)
+	if v, ok := reopened.Get("key"); !ok || string(v) != `{"ok":true}` {
+		t.Errorf("Expected cached value to survive reopening, got %q", v)
+	}
+	if _, ok := reopened.Get("expired"); ok {
+		t.Error("Expected expired entry to be missing")
+	}
+
+	if err := reopened.Clear(); err != nil {
+		t.Fatalf("Failed to clear cache: %v", err)
+	}
+	if _, ok := reopened.Get("key"); ok {
+		t.Error("Expected cleared entry to be missing")
+	}
+}
+
+func TestCacheTTLs(t *testing.T) {
+	ttls := DefaultCacheTTLs()
+
+	tests := []struct {
+		endpoint string
+		params   map[string]string
+		expected time.Duration
+	}{
+		{Query1URL + "/v7/finance/quote", nil, ttls.Quote},
+		{BaseURL + "/v10/finance/quoteSummary/AAPL", nil, ttls.QuoteSummary},
+		{BaseURL + "/v1/finance/search", nil, ttls.Search},
+		{BaseURL + "/v8/finance/chart/AAPL", map[string]string{"interval": "1m"}, 30 * time.Second},
+		{BaseURL + "/v8/finance/chart/AAPL", map[string]string{"interval": "1d"}, time.Hour},
+		{RootURL + "/xhr/ncp", nil, 0},
+	}
+
+	for _, tt := range tests {
+		if got := ttls.ttl(tt.endpoint, tt.params); got != tt.expected {
+			t.Errorf("ttl(%s, %v) = %v, expected %v", tt.endpoint, tt.params, got, tt.expected)
+		}
+	}
+}
+
+func TestWithCacheServesRepeatedRequests(t *testing.T) {
+	doer := &handlerDoer{h: fakeYahooHandler()}
+	cache := NewMemoryCache(0)
+	client := NewClient(WithHTTPClient(doer), WithCacheDir(""), WithCache(cache))
+
+	for i := 0; i < 3; i++ {
+		tz, err := client.Ticker("AAPL").GetTimezone(context.Background())
+		if err != nil || tz != "EST" {
+			t.Fatalf("Expected EST, got %q (%v)", tz, err)
+		}
+	}
+
+	var charts int
+	for _, u := range doer.urls {
+		if strings.Contains(u, "/v8/finance/chart/") {
+			charts++
+		}
+	}
+	if charts != 1 {
+		t.Errorf("Expected 1 chart request, got %d", charts)
+	}
+
+	// Requests with a zero TTL are never cached
+	client = NewClient(WithHTTPClient(doer), WithCacheDir(""), WithCache(cache), WithCacheTTLs(CacheTTLs{}))
+	if _, err := client.Ticker("MSFT").GetTimezone(context.Background()); err != nil {
+		t.Fatalf("Failed to get timezone: %v", err)
+	}
+	if cache.Len() != 1 {
+		t.Errorf("Expected 1 cache entry, got %d", cache.Len())
+	}
+}
+
+func TestConcurrentRequestsAreCoalesced(t *testing.T) {
+	var mu sync.Mutex
+	var charts int
+	doer := &handlerDoer{h: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/v8/finance/chart/") {
+			mu.Lock()
+			charts++
+			mu.Unlock()
+			time.Sleep(50 * time.Millisecond)
+		}
+		fakeYahooHandler().ServeHTTP(w, r)
+	})}
+	client := NewClient(WithHTTPClient(doer), WithCacheDir(""))
+	shared := client.Ticker("AAPL")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticker := shared
+			if i%2 == 0 {
+				ticker = client.Ticker("AAPL")
+			}
+			if tz, err := ticker.GetTimezone(context.Background()); err != nil || tz != "EST" {
+				t.Errorf("Expected EST, got %q (%v)", tz, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if charts != 1 {
+		t.Errorf("Expected 1 chart request, got %d", charts)
+	}
+}
+
+func TestCoalescedCallSurvivesLeaderCancel(t *testing.T) {
+	var g flightGroup
+	started := make(chan struct{})
+
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	go g.do(leaderCtx, "key", func(ctx context.Context) ([]byte, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	<-started
+
+	result := make(chan error, 1)
+	go func() {
+		body, err := g.do(context.Background(), "key", func(ctx context.Context) ([]byte, error) {
+			return []byte("ok"), nil
+		})
+		if err == nil && string(body) != "ok" {
+			err = errors.New("unexpected body " + string(body))
+		}
+		result <- err
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	if err := <-result; err != nil {
+		t.Errorf("Expected the follower to retry after the leader was cancelled, got %v", err)
+	}
+}
+
+// nullChartHandler serves a chart with a halted bar, a bar with a missing
+// close and columns shorter than the timestamps
+func nullChartHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "getcrumb") {
+			w.Write([]byte("testcrumb"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"chart":{"result":[{"meta":{"symbol":"TEST","timezone":"UTC"},
+			"timestamp":[1715002200,1715088600,1715175000,1715261400],
+			"indicators":{
+				"quote":[{"open":[10,null,11],"high":[11,null,12],"low":[9,null,10],
+					"close":[10.5,null,null],"volume":[100,null,300]}],
+				"adjclose":[{"adjclose":[10,null,null]}]}}],"error":null}}`))
+	})
+}
+
+func TestHistoryNullValues(t *testing.T) {
+	client := NewClient(WithHTTPClient(&handlerDoer{h: nullChartHandler()}), WithCacheDir(""))
+	ticker := client.Ticker("TEST")
+
+	history, err := ticker.History(context.Background(), &HistoryOptions{Period: "5d", Interval: "1d"})
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+
+	// Empty bars are dropped by default
+	if len(history.Data) != 2 {
+		t.Fatalf("Expected 2 bars, got %d", len(history.Data))
+	}
+	if history.Data[0].Missing != 0 || history.Data[0].Volume != 100 {
+		t.Errorf("Expected a complete first bar, got %+v", history.Data[0])
+	}
+
+	bar := history.Data[1]
+	if !math.IsNaN(bar.Close) || !math.IsNaN(bar.AdjClose) {
+		t.Errorf("Expected NaN closes, got %f and %f", bar.Close, bar.AdjClose)
+	}
+	if bar.Missing != FieldClose|FieldAdjClose || bar.Open != 11 || bar.Volume != 300 {
+		t.Errorf("Expected only the closes to be missing, got %+v", bar)
+	}
+
+	history, err = ticker.History(context.Background(), &HistoryOptions{Period: "5d", Interval: "1d", KeepNaN: true})
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+	if len(history.Data) != 4 {
+		t.Fatalf("Expected 4 bars with KeepNaN, got %d", len(history.Data))
+	}
+	for _, i := range []int{1, 3} {
+		if !history.Data[i].IsEmpty() || !math.IsNaN(history.Data[i].Open) {
+			t.Errorf("Expected bar %d to be empty, got %+v", i, history.Data[i])
+		}
+	}
+}
+
+func TestAutoAdjustUsesLastValidBar(t *testing.T) {
+	history := &HistoryResult{Data: []PriceData{
+		{Close: 100, AdjClose: 98},
+		{Close: 50, AdjClose: 49},
+		{Close: math.NaN(), AdjClose: math.NaN(), Missing: FieldClose | FieldAdjClose},
+	}}
+	history.AutoAdjustPrices()
+
+	if history.Data[0].AdjClose != 98 {
+		t.Errorf("Expected adjusted close 98, got %f", history.Data[0].AdjClose)
+	}
+}
+
+// bars builds daily bars with the given closes and an adjusted close 1%
+// below the close
+func bars(closes ...float64) []PriceData {
+	start := time.Date(2024, 5, 6, 13, 30, 0, 0, time.UTC)
+	data := make([]PriceData, len(closes))
+	for i, c := range closes {
+		data[i] = PriceData{
+			Date:     start.AddDate(0, 0, i),
+			Open:     c,
+			High:     c,
+			Low:      c,
+			Close:    c,
+			AdjClose: c * 0.99,
+			Volume:   1000,
+		}
+	}
+	return data
+}
+
+func TestRepairUnitMixups(t *testing.T) {
+	tests := []struct {
+		name     string
+		closes   []float64
+		expected []float64
+		repaired []bool
+	}{
+		{"isolated", []float64{100, 101, 10200, 102, 103}, []float64{100, 101, 102, 102, 103}, []bool{false, false, true, false, false}},
+		{"stretch", []float64{10000, 10100, 102, 103}, []float64{100, 101, 102, 103}, []bool{true, true, false, false}},
+		{"too low", []float64{100, 1.01, 102}, []float64{100, 101, 102}, []bool{false, true, false}},
+		{"clean", []float64{100, 130, 90}, []float64{100, 130, 90}, []bool{false, false, false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := bars(tt.closes...)
+			repairUnitMixups(data)
+			for i, bar := range data {
+				if math.Abs(bar.Close-tt.expected[i]) > 1e-9 || math.Abs(bar.Open-tt.expected[i]) > 1e-9 {
+					t.Errorf("Bar %d: expected %f, got open %f close %f", i, tt.expected[i], bar.Open, bar.Close)
+				}
+				if (bar.Repaired == RepairUnitMixup) != tt.repaired[i] {
+					t.Errorf("Bar %d: unexpected repair reason %s", i, bar.Repaired)
+				}
+			}
+		})
+	}
+}
+
+func TestRepairMissedSplits(t *testing.T) {
+	data := bars(200, 202, 204, 103, 104)
+	split := SplitData{Date: data[3].Date, Numerator: 2, Denominator: 1}
+
+	repairMissedSplits(data, []SplitData{split})
+	for i, expected := range []float64{100, 101, 102, 103, 104} {
+		if data[i].Close != expected {
+			t.Errorf("Bar %d: expected close %f, got %f", i, expected, data[i].Close)
+		}
+	}
+	if data[0].Volume != 2000 || data[0].Repaired != RepairSplit {
+		t.Errorf("Expected the volume to be split-adjusted, got %+v", data[0])
+	}
+	if data[3].Repaired != 0 {
+		t.Errorf("Expected bars from the split date on to be unchanged, got %s", data[3].Repaired)
+	}
+
+	// Bars that already are split-adjusted are left alone
+	data = bars(100, 101, 102, 103)
+	repairMissedSplits(data, []SplitData{{Date: data[2].Date, Numerator: 2, Denominator: 1}})
+	if data[0].Close != 100 || data[0].Repaired != 0 {
+		t.Errorf("Expected adjusted bars to be unchanged, got %+v", data[0])
+	}
+}
+
+func TestRepairBadBarsFromFinerData(t *testing.T) {
+	day := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -3).Add(13*time.Hour + 30*time.Minute)
+	daily := fmt.Sprintf(`{"chart":{"result":[{"meta":{"symbol":"TEST","timezone":"UTC"},
+		"timestamp":[%d,%d,%d],
+		"indicators":{"quote":[{"open":[10,0,12],"high":[11,13,13],"low":[9,9.5,11],
+			"close":[10.5,12,12.5],"volume":[100,0,300]}],
+			"adjclose":[{"adjclose":[10.5,12,12.5]}]}}],"error":null}}`,
+		day.Unix(), day.AddDate(0, 0, 1).Unix(), day.AddDate(0, 0, 2).Unix())
+	next := day.AddDate(0, 0, 1)
+	hourly := fmt.Sprintf(`{"chart":{"result":[{"meta":{"symbol":"TEST","timezone":"UTC"},
+		"timestamp":[%d,%d,%d],
+		"indicators":{"quote":[{"open":[10.6,11,12],"high":[11.2,13,12.4],"low":[9.5,10.8,11.5],
+			"close":[11,12,12],"volume":[40,50,60]}]}}],"error":null}}`,
+		next.Unix(), next.Add(time.Hour).Unix(), next.Add(2*time.Hour).Unix())
+
+	doer := &handlerDoer{h: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.Contains(r.URL.Path, "getcrumb"):
+			w.Write([]byte("testcrumb"))
+		case r.URL.Query().Get("interval") == "1h":
+			w.Write([]byte(hourly))
+		default:
+			w.Write([]byte(daily))
+		}
+	})}
+	client := NewClient(WithHTTPClient(doer), WithCacheDir(""))
+
+	history, err := client.Ticker("TEST").History(context.Background(), &HistoryOptions{
+		Period:   "5d",
+		Interval: "1d",
+		Repair:   true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+
+	bar := history.Data[1]
+	if bar.Open != 10.6 || bar.Volume != 150 {
+		t.Errorf("Expected open 10.6 and volume 150 from hourly bars, got %+v", bar)
+	}
+	if bar.High != 13 || bar.Close != 12 {
+		t.Errorf("Expected reported high and close to be kept, got %+v", bar)
+	}
+	if bar.Repaired != RepairMissing|RepairVolume {
+		t.Errorf("Expected missing|volume, got %s", bar.Repaired)
+	}
+	if history.Data[0].Repaired != 0 || history.Data[2].Repaired != 0 {
+		t.Error("Expected good bars to be unchanged")
+	}
+}
+
+// corporateActions returns split-adjusted bars, as Yahoo reports them,
+// with a 2:1 split before bar 2 and a dividend of 1.04 before bar 3
+func corporateActions() *HistoryResult {
+	data := bars(50, 51, 52, 50, 51)
+	for i := range data {
+		data[i].AdjClose = data[i].Close
+	}
+	return &HistoryResult{
+		Data:      data,
+		Splits:    []SplitData{{Date: data[2].Date, Numerator: 2, Denominator: 1}},
+		Dividends: []DividendData{{Date: data[3].Date, Amount: 1.04}},
+	}
+}
+
+func TestAutoAdjustPrices(t *testing.T) {
+	history := corporateActions()
+	history.AutoAdjustPrices()
+
+	factor := 1 - 1.04/52
+	for i, expected := range []float64{50 * factor, 51 * factor, 52 * factor, 50, 51} {
+		bar := history.Data[i]
+		if math.Abs(bar.Close-expected) > 1e-9 || math.Abs(bar.Open-expected) > 1e-9 || bar.AdjClose != bar.Close {
+			t.Errorf("Bar %d: expected %f, got open %f close %f adj %f", i, expected, bar.Open, bar.Close, bar.AdjClose)
+		}
+		if bar.Volume != 1000 {
+			t.Errorf("Bar %d: expected split-adjusted volume to be kept, got %d", i, bar.Volume)
+		}
+	}
+	if !history.AutoAdjusted {
+		t.Error("Expected AutoAdjusted to be set")
+	}
+
+	// Adjusting twice has no effect
+	history.AutoAdjustPrices()
+	if math.Abs(history.Data[0].Close-50*factor) > 1e-9 {
+		t.Errorf("Expected a second adjustment to be a no-op, got %f", history.Data[0].Close)
+	}
+}
+
+func TestAutoAdjustVolume(t *testing.T) {
+	// Yahoo's volumes are split-adjusted already; dividends leave them be
+	history := corporateActions()
+	history.Data[0].Volume = 2000
+	history.AutoAdjustPrices()
+	for i, expected := range []int64{2000, 1000, 1000, 1000, 1000} {
+		if history.Data[i].Volume != expected {
+			t.Errorf("Bar %d: expected volume %d, got %d", i, expected, history.Data[i].Volume)
+		}
+	}
+
+	// As-traded volumes are split-adjusted again
+	history = corporateActions()
+	history.Data[0].Volume = 2000
+	history.BackAdjustPrices()
+	if history.Data[0].Volume != 1000 {
+		t.Fatalf("Expected an as-traded volume of 1000, got %d", history.Data[0].Volume)
+	}
+	history.AutoAdjustPrices()
+	for i, expected := range []int64{2000, 1000, 1000, 1000, 1000} {
+		if history.Data[i].Volume != expected {
+			t.Errorf("Bar %d: expected back-adjusted volume %d to be restored, got %d", i, expected, history.Data[i].Volume)
+		}
+	}
+}
+
+func TestAutoAdjustCarriesLaterDividends(t *testing.T) {
+	history := corporateActions()
+	last := &history.Data[len(history.Data)-1]
+	last.AdjClose = last.Close * 0.98
+	history.AutoAdjustPrices()
+
+	if expected := 51 * 0.98; math.Abs(history.Data[4].Close-expected) > 1e-9 {
+		t.Errorf("Expected %f, got %f", expected, history.Data[4].Close)
+	}
+	if expected := 50 * (1 - 1.04/52) * 0.98; math.Abs(history.Data[0].Close-expected) > 1e-9 {
+		t.Errorf("Expected %f, got %f", expected, history.Data[0].Close)
+	}
+}
+
+func TestBackAdjustPrices(t *testing.T) {
+	history := corporateActions()
+	history.BackAdjustPrices()
+
+	for i, expected := range []float64{100, 102, 52, 50, 51} {
+		if history.Data[i].Close != expected {
+			t.Errorf("Bar %d: expected as-traded close %f, got %f", i, expected, history.Data[i].Close)
+		}
+	}
+	if history.Data[0].Volume != 500 || history.Data[2].Volume != 1000 {
+		t.Errorf("Expected as-traded volumes 500 and 1000, got %d and %d", history.Data[0].Volume, history.Data[2].Volume)
+	}
+	if history.Data[0].AdjClose != 50 {
+		t.Errorf("Expected AdjClose to stay adjusted, got %f", history.Data[0].AdjClose)
+	}
+
+	// Auto-adjusting back-adjusted data gives the same result as
+	// auto-adjusting Yahoo's data
+	history.AutoAdjustPrices()
+	expected := corporateActions()
+	expected.AutoAdjustPrices()
+	for i := range history.Data {
+		if math.Abs(history.Data[i].Close-expected.Data[i].Close) > 1e-9 || history.Data[i].Volume != expected.Data[i].Volume {
+			t.Errorf("Bar %d: expected %+v, got %+v", i, expected.Data[i], history.Data[i])
+		}
+	}
+}
+
+func TestHistoryRounding(t *testing.T) {
+	chart := func(priceHint string) *handlerDoer {
+		return &handlerDoer{h: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.Contains(r.URL.Path, "getcrumb") {
+				w.Write([]byte("testcrumb"))
+				return
+			}
+			fmt.Fprintf(w, `{"chart":{"result":[{"meta":{"symbol":"EURUSD=X","timezone":"UTC"%s},
+				"timestamp":[1715002200],
+				"indicators":{"quote":[{"open":[1.076543],"high":[1.078912],"low":[1.071234],
+					"close":[1.075555],"volume":[0]}],"adjclose":[{"adjclose":[1.075555]}]}}],"error":null}}`, priceHint)
+		})}
+	}
+	one := 1
+
+	tests := []struct {
+		name      string
+		priceHint string
+		options   HistoryOptions
+		expected  float64
+	}{
+		{"disabled", `,"priceHint":4`, HistoryOptions{}, 1.075555},
+		{"price hint", `,"priceHint":4`, HistoryOptions{Rounding: true}, 1.0756},
+		{"no price hint", ``, HistoryOptions{Rounding: true}, 1.08},
+		{"override", `,"priceHint":4`, HistoryOptions{Rounding: true, Precision: &one}, 1.1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(WithHTTPClient(chart(tt.priceHint)), WithCacheDir(""))
+			options := tt.options
+			options.Period = "1d"
+			options.Interval = "1d"
+
+			history, err := client.Ticker("EURUSD=X").History(context.Background(), &options)
+			if err != nil {
+				t.Fatalf("Failed to get history: %v", err)
+			}
+			if bar := history.Data[0]; bar.Close != tt.expected || bar.AdjClose != tt.expected {
+				t.Errorf("Expected close %v, got %v (adj %v)", tt.expected, bar.Close, bar.AdjClose)
+			}
+		})
+	}
+
+	// Downloads pass the precision on to each ticker
+	client := NewClient(WithHTTPClient(chart(`,"priceHint":4`)), WithCacheDir(""))
+	result, err := client.Download(context.Background(), &DownloadOptions{
+		Tickers:   []string{"EURUSD=X"},
+		Period:    "1d",
+		Interval:  "1d",
+		Rounding:  true,
+		Precision: &one,
+	})
+	if err != nil {
+		t.Fatalf("Failed to download: %v", err)
+	}
+	if bar := result.Data["EURUSD=X"].Data[0]; bar.Close != 1.1 {
+		t.Errorf("Expected downloaded close 1.1, got %v", bar.Close)
+	}
+}
+
+func TestHistoryEvents(t *testing.T) {
+	doer := &handlerDoer{h: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "getcrumb") {
+			w.Write([]byte("testcrumb"))
+			return
+		}
+		w.Write([]byte(`{"chart":{"result":[{"meta":{"symbol":"VFIAX","timezone":"UTC"},
+			"timestamp":[1700000000,1710000000,1720000000],
+			"events":{
+				"dividends":{"1715000000":{"amount":1.5,"date":1715000000},"1705000000":{"amount":1.4,"date":1705000000},
+					"1701000000":{"amount":1.3,"date":1701000000}},
+				"splits":{"1716000000":{"numerator":2,"denominator":1},"1706000000":{"numerator":3,"denominator":1}},
+				"capitalGains":{"1718000000":{"amount":0.2,"date":1718000000},"1702000000":{"amount":0.1,"date":1702000000}}},
+			"indicators":{"quote":[{"open":[10,11,12],"high":[10,11,12],"low":[10,11,12],"close":[10,11,12],"volume":[1,1,1]}],
+				"adjclose":[{"adjclose":[10,11,12]}]}}],"error":null}}`))
+	})}
+	client := NewClient(WithHTTPClient(doer), WithCacheDir(""))
+
+	history, err := client.Ticker("VFIAX").History(context.Background(), &HistoryOptions{Period: "1y", Interval: "1d"})
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+	if !doer.seen("events=div%2Csplits%2CcapitalGains") {
+		t.Error("Expected all events to be requested")
+	}
+
+	var dividends, splits, gains []int64
+	for _, d := range history.Dividends {
+		dividends = append(dividends, d.Date.Unix())
+	}
+	for _, s := range history.Splits {
+		splits = append(splits, s.Date.Unix())
+	}
+	for _, g := range history.CapitalGains {
+		gains = append(gains, g.Date.Unix())
+	}
+	if fmt.Sprint(dividends) != "[1701000000 1705000000 1715000000]" {
+		t.Errorf("Expected sorted dividends, got %v", dividends)
+	}
+	if fmt.Sprint(splits) != "[1706000000 1716000000]" {
+		t.Errorf("Expected sorted splits, got %v", splits)
+	}
+	if fmt.Sprint(gains) != "[1702000000 1718000000]" || history.CapitalGains[1].Amount != 0.2 {
+		t.Errorf("Expected sorted capital gains, got %+v", history.CapitalGains)
+	}
+}
+
+// sessionChart serves a 1m chart of one day with a bar in each session.
+// tradingPeriods is the raw JSON Yahoo sends for it.
+func sessionChart(tradingPeriods string) *handlerDoer {
+	return &handlerDoer{h: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "getcrumb") {
+			w.Write([]byte("testcrumb"))
+			return
+		}
+		fmt.Fprintf(w, `{"chart":{"result":[{"meta":{"symbol":"AAPL","timezone":"America/New_York",
+			"currentTradingPeriod":{
+				"pre":{"timezone":"EDT","start":1717488000,"end":1717507800,"gmtoffset":-14400},
+				"regular":{"timezone":"EDT","start":1717507800,"end":1717531200,"gmtoffset":-14400},
+				"post":{"timezone":"EDT","start":1717531200,"end":1717545600,"gmtoffset":-14400}},
+			"tradingPeriods":%s},
+			"timestamp":[1717405200,1717421400,1717448400,1717491600,1717520400,1717538400],
+			"indicators":{"quote":[{"open":[1,2,3,4,5,6],"high":[1,2,3,4,5,6],"low":[1,2,3,4,5,6],
+				"close":[1,2,3,4,5,6],"volume":[1,1,1,1,1,1]}]}}],"error":null}}`, tradingPeriods)
+	})}
+}
+
+func TestHistorySessions(t *testing.T) {
+	// Trading periods of 2024-06-03 only; the bars of 2024-06-04 are tagged
+	// from the current trading period
+	prePost := `{
+		"pre":[[{"timezone":"EDT","start":1717401600,"end":1717421400,"gmtoffset":-14400}]],
+		"regular":[[{"timezone":"EDT","start":1717421400,"end":1717444800,"gmtoffset":-14400}]],
+		"post":[[{"timezone":"EDT","start":1717444800,"end":1717459200,"gmtoffset":-14400}]]}`
+	client := NewClient(WithHTTPClient(sessionChart(prePost)), WithCacheDir(""))
+
+	history, err := client.Ticker("AAPL").History(context.Background(), &HistoryOptions{
+		Period:   "5d",
+		Interval: "1m",
+		PrePost:  true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+
+	expected := []Session{SessionPre, SessionRegular, SessionPost, SessionPre, SessionRegular, SessionPost}
+	for i, bar := range history.Data {
+		if bar.Session != expected[i] {
+			t.Errorf("Bar %d at %v: expected %s, got %s", i, bar.Date, expected[i], bar.Session)
+		}
+	}
+
+	if len(history.Meta.TradingPeriods) != 1 {
+		t.Fatalf("Expected 1 trading day, got %d", len(history.Meta.TradingPeriods))
+	}
+	day := history.Meta.TradingPeriods[0]
+	if day.Regular.Start.Unix() != 1717421400 || day.Post.End.Unix() != 1717459200 || day.Regular.Timezone != "EDT" {
+		t.Errorf("Unexpected trading day: %+v", day)
+	}
+	if history.Meta.CurrentTradingPeriod.Regular.Start.Unix() != 1717507800 {
+		t.Errorf("Unexpected current trading period: %+v", history.Meta.CurrentTradingPeriod)
+	}
+}
+
+func TestHistoryRegularTradingPeriods(t *testing.T) {
+	// Without includePrePost, Yahoo only lists the regular sessions
+	regular := `[[{"timezone":"EDT","start":1717421400,"end":1717444800,"gmtoffset":-14400}],
+		[{"timezone":"EDT","start":1717507800,"end":1717531200,"gmtoffset":-14400}]]`
+	client := NewClient(WithHTTPClient(sessionChart(regular)), WithCacheDir(""))
+
+	history, err := client.Ticker("AAPL").History(context.Background(), &HistoryOptions{
+		Period:   "5d",
+		Interval: "1m",
+	})
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+
+	if len(history.Meta.TradingPeriods) != 2 {
+		t.Fatalf("Expected 2 trading days, got %d", len(history.Meta.TradingPeriods))
+	}
+	if day := history.Meta.TradingPeriods[1]; day.Regular.Start.Unix() != 1717507800 || !day.Pre.IsZero() {
+		t.Errorf("Unexpected trading day: %+v", day)
+	}
+	if history.Data[1].Session != SessionUnknown {
+		t.Errorf("Expected bars to be untagged without PrePost, got %s", history.Data[1].Session)
+	}
+}
+
+// minuteBars returns n one-minute bars from start, with open i, high i+0.5,
+// low i-0.5, close i+0.25 and volume 10 for bar i
+func minuteBars(start time.Time, n int) []PriceData {
+	var data []PriceData
+	for i := 0; i < n; i++ {
+		v := float64(i)
+		data = append(data, PriceData{
+			Date:     start.Add(time.Duration(i) * time.Minute),
+			Open:     v,
+			High:     v + 0.5,
+			Low:      v - 0.5,
+			Close:    v + 0.25,
+			AdjClose: v + 0.25,
+			Volume:   10,
+		})
+	}
+	return data
+}
+
+func TestResampleMinutes(t *testing.T) {
+	ny, _ := time.LoadLocation("America/New_York")
+	history := &HistoryResult{
+		Timezone: "America/New_York",
+		Data:     minuteBars(time.Date(2024, 6, 3, 9, 30, 0, 0, ny), 10),
+	}
+	history.Data[4].Missing = FieldOpen | FieldVolume
+	history.Data[4].Open = math.NaN()
+
+	resampled, err := history.Resample("3m")
+	if err != nil {
+		t.Fatalf("Failed to resample: %v", err)
+	}
+	if len(resampled.Data) != 4 {
+		t.Fatalf("Expected 4 bars, got %d", len(resampled.Data))
+	}
+
+	bar := resampled.Data[1]
+	if bar.Date.Hour() != 9 || bar.Date.Minute() != 33 {
+		t.Errorf("Expected the bucket to start at 09:33, got %v", bar.Date)
+	}
+	if bar.Open != 3 || bar.High != 5.5 || bar.Low != 2.5 || bar.Close != 5.25 || bar.Volume != 20 {
+		t.Errorf("Unexpected bar: %+v", bar)
+	}
+	if bar.Missing != 0 {
+		t.Errorf("Expected no missing fields, got %v", bar.Missing)
+	}
+	if last := resampled.Data[3]; last.Open != 9 || last.Volume != 10 {
+		t.Errorf("Expected the partial last bucket to hold bar 9, got %+v", last)
+	}
+
+	if _, err := history.Resample("3x"); err == nil {
+		t.Error("Expected an error for an unknown unit")
+	}
+}
+
+func TestResampleSessionAligned(t *testing.T) {
+	ny, _ := time.LoadLocation("America/New_York")
+	open := time.Date(2024, 6, 3, 9, 30, 0, 0, ny)
+	history := &HistoryResult{
+		Timezone: "America/New_York",
+		Data:     minuteBars(open, 390),
+		Meta: HistoryMeta{
+			TradingPeriods: []TradingDay{{Regular: TradingPeriod{Start: open, End: open.Add(390 * time.Minute)}}},
+		},
+	}
+
+	for _, tt := range []struct {
+		name   string
+		opts   []ResampleOption
+		starts []int
+	}{
+		{"midnight", nil, []int{8, 12}},
+		{"session", []ResampleOption{AlignToSession()}, []int{9, 13}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			resampled, err := history.Resample("4h", tt.opts...)
+			if err != nil {
+				t.Fatalf("Failed to resample: %v", err)
+			}
+			if len(resampled.Data) != len(tt.starts) {
+				t.Fatalf("Expected %d bars, got %d", len(tt.starts), len(resampled.Data))
+			}
+			for i, hour := range tt.starts {
+				if resampled.Data[i].Date.Hour() != hour {
+					t.Errorf("Bar %d: expected start hour %d, got %v", i, hour, resampled.Data[i].Date)
+				}
+			}
+		})
+	}
+}
+
+func TestResampleWeeksAndEvents(t *testing.T) {
+	// Daily bars from Wednesday 2024-05-01 to Friday 2024-05-17
+	var data []PriceData
+	for day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC); day.Day() <= 17; day = day.AddDate(0, 0, 1) {
+		if day.Weekday() != time.Saturday && day.Weekday() != time.Sunday {
+			data = append(data, PriceData{Date: day, Open: 1, High: 1, Low: 1, Close: 1, AdjClose: 1, Volume: 1})
+		}
+	}
+	history := &HistoryResult{
+		Timezone:  "UTC",
+		Data:      data,
+		Dividends: []DividendData{{Date: time.Date(2024, 5, 8, 0, 0, 0, 0, time.UTC), Amount: 0.2}, {Date: time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC), Amount: 0.3}},
+		Splits:    []SplitData{{Date: time.Date(2024, 5, 13, 0, 0, 0, 0, time.UTC), Numerator: 2, Denominator: 1}, {Date: time.Date(2024, 5, 14, 0, 0, 0, 0, time.UTC), Numerator: 3, Denominator: 1}},
+	}
+
+	weekly, err := history.Resample("1wk")
+	if err != nil {
+		t.Fatalf("Failed to resample: %v", err)
+	}
+	var starts []string
+	var volumes []int64
+	for _, bar := range weekly.Data {
+		starts = append(starts, bar.Date.Format("01-02"))
+		volumes = append(volumes, bar.Volume)
+	}
+	if fmt.Sprint(starts) != "[04-29 05-06 05-13]" || fmt.Sprint(volumes) != "[3 5 5]" {
+		t.Errorf("Unexpected Monday weeks %v with volumes %v", starts, volumes)
+	}
+	if len(weekly.Dividends) != 1 || weekly.Dividends[0].Amount != 0.5 || weekly.Dividends[0].Date.Day() != 6 {
+		t.Errorf("Expected the dividends to be summed into the week of 05-06, got %+v", weekly.Dividends)
+	}
+	if len(weekly.Splits) != 1 || weekly.Splits[0].Ratio != "6:1" {
+		t.Errorf("Expected the splits to be combined, got %+v", weekly.Splits)
+	}
+
+	sunday, _ := history.Resample("1wk", WeekStartingOn(time.Sunday))
+	if sunday.Data[0].Date.Format("01-02") != "04-28" {
+		t.Errorf("Expected the first week to start on Sunday 04-28, got %v", sunday.Data[0].Date)
+	}
+
+	quarterly, _ := history.Resample("3mo")
+	if len(quarterly.Data) != 1 || quarterly.Data[0].Date.Format("01-02") != "04-01" {
+		t.Errorf("Expected one quarter starting 04-01, got %+v", quarterly.Data)
+	}
+}
+
+func TestNewPanel(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 5, d, 13, 30, 0, 0, time.UTC) }
+	bar := func(d int, close float64) PriceData {
+		return PriceData{Date: day(d), Open: close, High: close, Low: close, Close: close, AdjClose: close, Volume: 100}
+	}
+	aaa := &HistoryResult{Data: []PriceData{bar(1, 10), bar(2, 11), bar(3, 12)}}
+	aaa.Data[1].Close, aaa.Data[1].Missing = math.NaN(), FieldClose
+	bbb := &HistoryResult{Data: []PriceData{bar(2, 20), bar(4, 21)}}
+
+	panel, err := NewPanel(map[string]*HistoryResult{"BBB": bbb, "AAA": aaa}, PanelOptions{GroupBy: "ticker"})
+	if err != nil {
+		t.Fatalf("Failed to build panel: %v", err)
+	}
+	if panel.Len() != 4 || fmt.Sprint(panel.Tickers) != "[AAA BBB]" {
+		t.Fatalf("Expected 4 rows for AAA and BBB, got %d for %v", panel.Len(), panel.Tickers)
+	}
+
+	closes := panel.Column(FieldClose, "AAA")
+	if fmt.Sprint(closes.Values) != "[10 NaN 12 NaN]" || fmt.Sprint(closes.Missing) != "[false true false true]" {
+		t.Errorf("Unexpected AAA closes %v, missing %v", closes.Values, closes.Missing)
+	}
+	if opens := panel.Column(FieldOpen, "AAA"); opens.Missing[1] || opens.Values[1] != 11 {
+		t.Errorf("Expected the open of the bar without close, got %v", opens.Values)
+	}
+	if volumes := panel.Column(FieldVolume, "BBB"); fmt.Sprint(volumes.Values) != "[NaN 100 NaN 100]" {
+		t.Errorf("Unexpected BBB volumes %v", volumes.Values)
+	}
+
+	columns := panel.Columns()
+	if columns[0].Ticker != "AAA" || columns[1].Ticker != "AAA" || columns[1].Field != FieldHigh {
+		t.Errorf("Expected columns grouped by ticker, got %s/%s, %s/%s",
+			columns[0].Ticker, columns[0].Field, columns[1].Ticker, columns[1].Field)
+	}
+	panel.GroupBy = "column"
+	if columns = panel.Columns(); columns[1].Ticker != "BBB" || columns[1].Field != FieldOpen {
+		t.Errorf("Expected columns grouped by field, got %s/%s", columns[1].Ticker, columns[1].Field)
+	}
+
+	if _, err := NewPanel(nil, PanelOptions{GroupBy: "sector"}); err == nil {
+		t.Error("Expected an error for an invalid GroupBy")
+	}
+}
+
+func TestNewPanelNormalizeDates(t *testing.T) {
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	ny, _ := time.LoadLocation("America/New_York")
+	histories := map[string]*HistoryResult{
+		"7203.T": {Data: []PriceData{{Date: time.Date(2024, 5, 2, 9, 0, 0, 0, tokyo), Close: 1}}},
+		"AAPL":   {Data: []PriceData{{Date: time.Date(2024, 5, 2, 9, 30, 0, 0, ny), Close: 2}}},
+	}
+
+	panel, _ := NewPanel(histories, PanelOptions{})
+	if panel.Len() != 2 {
+		t.Errorf("Expected the exchange timestamps to differ, got %d rows", panel.Len())
+	}
+
+	panel, _ = NewPanel(histories, PanelOptions{NormalizeDates: true})
+	if panel.Len() != 1 || !panel.Index[0].Equal(time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Expected one row on 2024-05-02, got %v", panel.Index)
+	}
+	if panel.Column(FieldClose, "7203.T").Values[0] != 1 || panel.Column(FieldClose, "AAPL").Values[0] != 2 {
+		t.Error("Expected both tickers on the same row")
+	}
+}
+
+func TestHistoryChunks(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	daysAgo := func(days int) *time.Time {
+		start := now.AddDate(0, 0, -days)
+		return &start
+	}
+
+	tests := []struct {
+		name     string
+		options  HistoryOptions
+		expected int
+	}{
+		{"daily interval", HistoryOptions{Period: "max", Interval: "1d"}, 0},
+		{"fits one request", HistoryOptions{Period: "5d", Interval: "1m"}, 0},
+		{"start within span", HistoryOptions{Start: daysAgo(6), Interval: "1m"}, 0},
+		{"start beyond span", HistoryOptions{Start: daysAgo(20), Interval: "1m"}, 3},
+		{"five minute month", HistoryOptions{Period: "1mo", Interval: "5m"}, 0},
+		{"hourly two years", HistoryOptions{Period: "1y", Interval: "1h"}, 0},
+		{"intraday max", HistoryOptions{Period: "max", Interval: "1m"}, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks, err := historyChunks("TEST", &tt.options, now)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(chunks) != tt.expected {
+				t.Fatalf("Expected %d chunks, got %d", tt.expected, len(chunks))
+			}
+			for i := 1; i < len(chunks); i++ {
+				if !chunks[i].start.Equal(chunks[i-1].end) {
+					t.Errorf("Chunk %d does not start where chunk %d ends", i, i-1)
+				}
+			}
+		})
+	}
+}
+
+func TestHistoryLookbackError(t *testing.T) {
+	client := NewClient(WithHTTPClient(&handlerDoer{h: fakeYahooHandler()}), WithCacheDir(""))
+
+	start := time.Now().AddDate(0, 0, -45)
+	_, err := client.Ticker("TEST").History(context.Background(), &HistoryOptions{
+		Start:    &start,
+		Interval: "1m",
+	})
+
+	var lookback *YFLookbackError
+	if !errors.As(err, &lookback) {
+		t.Fatalf("Expected a lookback error, got %v", err)
+	}
+	if lookback.Interval != "1m" || lookback.Lookback != 30*24*time.Hour {
+		t.Errorf("Unexpected lookback error: %+v", lookback)
+	}
+
+	// 1m bars go back 30 days, fetched 7 days at a time
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC)
+	inside := now.Add(-30*24*time.Hour + time.Minute)
+	chunks, err := historyChunks("TEST", &HistoryOptions{Start: &inside, End: &now, Interval: "1m"}, now)
+	if err != nil || len(chunks) != 5 {
+		t.Errorf("Expected 5 chunks just inside the 1m lookback, got %d, %v", len(chunks), err)
+	}
+	outside := now.Add(-30*24*time.Hour - time.Minute)
+	if _, err := historyChunks("TEST", &HistoryOptions{Start: &outside, End: &now, Interval: "1m"}, now); !errors.As(err, &lookback) {
+		t.Errorf("Expected a lookback error just outside the 1m lookback, got %v", err)
+	}
+
+	// Periods longer than the lookback after a 31-day month or across a
+	// leap day are clamped to it
+	now = time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC)
+	for _, tt := range []struct{ period, interval string }{{"1mo", "1m"}, {"2y", "1h"}} {
+		earliest := now.Add(-IntervalLimits[tt.interval].MaxLookback)
+		chunks, err := historyChunks("TEST", &HistoryOptions{Period: tt.period, Interval: tt.interval}, now)
+		if err != nil || len(chunks) == 0 {
+			t.Errorf("Expected %s %s to be clamped to the lookback, got %d chunks, %v", tt.period, tt.interval, len(chunks), err)
+			continue
+		}
+		if chunks[0].start.Before(earliest) || !chunks[len(chunks)-1].end.Equal(now) {
+			t.Errorf("Expected %s %s chunks within the lookback, got %v to %v", tt.period, tt.interval, chunks[0].start, chunks[len(chunks)-1].end)
+		}
+	}
+}
+
+// sixHourStep is the spacing of the bars served by rangeChart
+const sixHourStep = int64(6 * 3600)
+
+// rangeChart serves a bar every 6 hours within [period1, period2], so that
+// the bar on each chunk boundary is returned twice. The returned function
+// counts the chart requests for a range.
+func rangeChart() (*handlerDoer, func() int) {
+	var mu sync.Mutex
+	var requests int
+	doer := &handlerDoer{h: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "getcrumb") {
+			w.Write([]byte("testcrumb"))
+			return
+		}
+		if r.URL.Query().Get("period1") == "" {
+			fakeYahooHandler().ServeHTTP(w, r)
+			return
+		}
+		mu.Lock()
+		requests++
+		mu.Unlock()
+
+		var from, to int64
+		fmt.Sscan(r.URL.Query().Get("period1"), &from)
+		fmt.Sscan(r.URL.Query().Get("period2"), &to)
+		var timestamps, closes []string
+		for ts := (from + sixHourStep - 1) / sixHourStep * sixHourStep; ts <= to; ts += sixHourStep {
+			timestamps = append(timestamps, fmt.Sprint(ts))
+			closes = append(closes, fmt.Sprint(ts%1000))
+		}
+		list := func(values []string) string { return "[" + strings.Join(values, ",") + "]" }
+		fmt.Fprintf(w, `{"chart":{"result":[{"meta":{"symbol":"TEST","timezone":"UTC"},
+			"timestamp":%s,"indicators":{"quote":[{"open":%[2]s,"high":%[2]s,"low":%[2]s,"close":%[2]s}]}}],"error":null}}`,
+			list(timestamps), list(closes))
+	})}
+	return doer, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests
+	}
+}
+
+func TestHistoryChunkedFetch(t *testing.T) {
+	doer, requests := rangeChart()
+	client := NewClient(WithHTTPClient(doer), WithCacheDir(""), WithMaxConcurrency(2))
+
+	end := time.Now().UTC().Truncate(time.Hour)
+	start := end.AddDate(0, 0, -20)
+	history, err := client.Ticker("TEST").History(context.Background(), &HistoryOptions{
+		Start:    &start,
+		End:      &end,
+		Interval: "1m",
+	})
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+
+	if requests() != 3 {
+		t.Errorf("Expected 3 chunk requests, got %d", requests())
+	}
+	first := (start.Unix() + sixHourStep - 1) / sixHourStep * sixHourStep
+	expected := int((end.Unix()-first)/sixHourStep) + 1
+	if len(history.Data) != expected {
+		t.Fatalf("Expected %d bars, got %d", expected, len(history.Data))
+	}
+	for i := 1; i < len(history.Data); i++ {
+		if history.Data[i].Date.Sub(history.Data[i-1].Date) != 6*time.Hour {
+			t.Fatalf("Expected sorted bars without duplicates, bar %d at %v follows %v",
+				i, history.Data[i].Date, history.Data[i-1].Date)
+		}
+	}
+}
+
+func TestHistoryIter(t *testing.T) {
+	doer, requests := rangeChart()
+	client := NewClient(WithHTTPClient(doer), WithCacheDir(""))
+	ticker := client.Ticker("TEST")
+
+	end := time.Now().UTC().Truncate(time.Hour)
+	start := end.AddDate(0, 0, -20)
+	options := &HistoryOptions{Start: &start, End: &end, Interval: "1m"}
+
+	history, err := ticker.History(context.Background(), options)
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+
+	var dates []time.Time
+	for bar, err := range ticker.HistoryIter(context.Background(), options) {
+		if err != nil {
+			t.Fatalf("Failed to iterate: %v", err)
+		}
+		dates = append(dates, bar.Date)
+	}
+	if len(dates) != len(history.Data) {
+		t.Fatalf("Expected %d bars, got %d", len(history.Data), len(dates))
+	}
+	for i, date := range dates {
+		if !date.Equal(history.Data[i].Date) {
+			t.Fatalf("Bar %d: expected %v, got %v", i, history.Data[i].Date, date)
+		}
+	}
+
+	// Stopping early leaves the remaining chunks unfetched
+	before := requests()
+	for range ticker.HistoryIter(context.Background(), options) {
+		break
+	}
+	if fetched := requests() - before; fetched != 1 {
+		t.Errorf("Expected 1 chunk request, got %d", fetched)
+	}
+
+	tooOld := end.AddDate(0, 0, -45)
+	for _, err := range ticker.HistoryIter(context.Background(), &HistoryOptions{Start: &tooOld, Interval: "1m"}) {
+		var lookback *YFLookbackError
+		if !errors.As(err, &lookback) {
+			t.Errorf("Expected a lookback error, got %v", err)
+		}
+	}
+}
+
+func TestDownloadStream(t *testing.T) {
+	doer, _ := rangeChart()
+	client := NewClient(WithHTTPClient(doer), WithCacheDir(""))
+
+	end := time.Now()
+	start := end.AddDate(0, 0, -3)
+	items := client.DownloadStream(context.Background(), &DownloadOptions{
+		Tickers:  []string{"AAA", "BBB,CCC", "aaa"},
+		Start:    &start,
+		Interval: "1d",
+		Period:   "bogus",
+		Threads:  2,
+	})
+
+	seen := make(map[string]bool)
+	for item := range items {
+		seen[item.Ticker] = true
+		if item.Err == nil {
+			t.Errorf("Expected %s to fail with an invalid period", item.Ticker)
+		}
+	}
+	if len(seen) != 3 || !seen["AAA"] || !seen["BBB"] || !seen["CCC"] {
+		t.Errorf("Expected one item per ticker, got %v", seen)
+	}
+
+	result, err := client.Download(context.Background(), &DownloadOptions{
+		Tickers:  []string{"AAA", "BBB"},
+		Start:    &start,
+		End:      &end,
+		Interval: "1d",
+		Threads:  2,
+	})
+	if err != nil {
+		t.Fatalf("Failed to download: %v", err)
+	}
+	if len(result.Succeeded) != 2 || len(result.Data["BBB"].Data) == 0 {
+		t.Errorf("Expected both tickers to succeed, got %+v", result)
+	}
+	if result.Panel == nil || result.Panel.Len() != len(result.Data["BBB"].Data) {
+		t.Errorf("Expected a panel over the downloaded bars, got %+v", result.Panel)
+	}
+
+	if _, err := client.Download(context.Background(), &DownloadOptions{Tickers: []string{"AAA"}, GroupBy: "sector"}); err == nil {
+		t.Error("Expected an error for an invalid GroupBy")
+	}
+}
+
+func TestDownloadProgress(t *testing.T) {
+	var mu sync.Mutex
+	retried := false
+	doer := &handlerDoer{h: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case strings.Contains(r.URL.Path, "/chart/FAIL"):
+			w.WriteHeader(http.StatusNotFound)
+		case strings.Contains(r.URL.Path, "/chart/RETRY") && !retried:
+			retried = true
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			fakeYahooHandler().ServeHTTP(w, r)
+		}
+	})}
+	policy := DefaultRetryPolicy()
+	policy.BaseDelay = time.Millisecond
+	client := NewClient(WithHTTPClient(doer), WithCacheDir(""), WithRetryPolicy(policy))
+
+	var events []ProgressEvent
+	var finished []string
+	var bar strings.Builder
+	showBar := NewProgressBar(&bar)
+	_, err := client.Download(context.Background(), &DownloadOptions{
+		Tickers:  []string{"OK", "RETRY", "FAIL"},
+		Period:   "1d",
+		Interval: "1d",
+		Threads:  3,
+		OnProgress: func(e ProgressEvent) {
+			events = append(events, e)
+			showBar(e)
+		},
+		OnTicker: func(item DownloadItem) {
+			finished = append(finished, item.Ticker)
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to download: %v", err)
+	}
+
+	kinds := make(map[ProgressKind][]string)
+	for _, e := range events {
+		kinds[e.Kind] = append(kinds[e.Kind], e.Ticker)
+	}
+	if len(kinds[ProgressStarted]) != 3 || len(kinds[ProgressSucceeded]) != 2 {
+		t.Errorf("Expected 3 started and 2 succeeded, got %v", kinds)
+	}
+	if fmt.Sprint(kinds[ProgressFailed]) != "[FAIL]" || fmt.Sprint(kinds[ProgressRetried]) != "[RETRY]" {
+		t.Errorf("Expected FAIL to fail and RETRY to be retried, got %v", kinds)
+	}
+
+	last := events[len(events)-1]
+	if last.Total != 3 || last.Done() != 3 || last.Failed != 1 || last.Retries != 1 || last.ETA() != 0 {
+		t.Errorf("Unexpected final counts: %+v", last)
+	}
+	if len(finished) != 3 {
+		t.Errorf("Expected OnTicker for every ticker, got %v", finished)
+	}
+	if output := bar.String(); !strings.Contains(output, "] 3/3 100% 1 failed in ") || !strings.HasSuffix(output, "\n") {
+		t.Errorf("Unexpected progress bar output %q", output)
+	}
+}
+
+func TestUseSpark(t *testing.T) {
+	start := time.Now().AddDate(0, 0, -5)
+	defaults := *DefaultDownloadOptions()
+	defaults.CloseOnly = true
+	unadjusted := defaults
+	unadjusted.AutoAdjust = false
+	tests := []struct {
+		name     string
+		options  DownloadOptions
+		expected bool
+	}{
+		{"close only", DownloadOptions{CloseOnly: true, Period: "1mo", Interval: "1d"}, true},
+		{"default options", defaults, false},
+		{"default options unadjusted", unadjusted, true},
+		{"intraday", DownloadOptions{CloseOnly: true, Period: "5d", Interval: "5m"}, true},
+		{"full bars", DownloadOptions{Period: "1mo", Interval: "1d"}, false},
+		{"auto adjust", DownloadOptions{CloseOnly: true, Period: "1mo", Interval: "1d", AutoAdjust: true}, false},
+		{"back adjust", DownloadOptions{CloseOnly: true, Period: "1mo", Interval: "1d", BackAdjust: true}, false},
+		{"repair", DownloadOptions{CloseOnly: true, Period: "1mo", Interval: "1d", Repair: true}, false},
+		{"start", DownloadOptions{CloseOnly: true, Start: &start, Interval: "1d"}, false},
+		{"chunked", DownloadOptions{CloseOnly: true, Period: "max", Interval: "1m"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			histOpts := downloadHistoryOptions(&tt.options)
+			if got := useSpark(&tt.options, histOpts); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+			if histOpts.AutoAdjust != tt.options.AutoAdjust {
+				t.Error("Expected the caller's AutoAdjust to be kept")
+			}
+		})
+	}
+}
+
+func TestMaxConcurrency(t *testing.T) {
+	var mu sync.Mutex
+	var active, peak int
+	doer := &handlerDoer{h: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		active++
+		peak = max(peak, active)
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		active--
+		mu.Unlock()
+		fakeYahooHandler().ServeHTTP(w, r)
+	})}
+	client := NewClient(WithHTTPClient(doer), WithCacheDir(""), WithMaxConcurrency(2))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client.Ticker(fmt.Sprintf("TEST%d", i)).GetTimezone(context.Background())
+		}()
+	}
+	wg.Wait()
+
+	if peak > 2 {
+		t.Errorf("Expected at most 2 concurrent requests, got %d", peak)
+	}
+}
+
+func TestParseQuote(t *testing.T) {
+	quote := parseQuote(map[string]interface{}{
+		"symbol":                      "AAPL",
+		"exchange":                    "NMS",
+		"fullExchangeName":            "NasdaqGS",
+		"marketState":                 "POST",
+		"regularMarketPrice":          183.05,
+		"regularMarketVolume":         float64(50759500),
+		"regularMarketTime":           float64(1715371200),
+		"bid":                         183.0,
+		"askSize":                     float64(2),
+		"tradeable":                   true,
+		"trailingAnnualDividendYield": 0.0052,
+		"dividendYield":               0.55,
+		"firstTradeDateMilliseconds":  float64(345479400000),
+		"customPriceAlertConfidence":  "HIGH",
+	})
+
+	if quote.Symbol != "AAPL" || quote.Exchange != "NMS" || quote.FullExchangeName != "NasdaqGS" || quote.MarketState != "POST" {
+		t.Errorf("Unexpected identity fields: %+v", quote)
+	}
+	if quote.RegularMarketPrice != 183.05 || quote.RegularMarketVolume != 50759500 || quote.RegularMarketTime.Unix() != 1715371200 {
+		t.Errorf("Unexpected price fields: %+v", quote)
+	}
+	if quote.Bid != 183 || quote.AskSize != 2 || !quote.Tradeable {
+		t.Errorf("Unexpected order book fields: %+v", quote)
+	}
+	if quote.DividendYield != 0.55 || quote.TrailingAnnualDividendYield != 0.0052 {
+		t.Errorf("Unexpected dividend yields: %+v", quote)
+	}
+	if quote.FirstTradeDate.UnixMilli() != 345479400000 {
+		t.Errorf("Unexpected first trade date: %v", quote.FirstTradeDate)
+	}
+	if len(quote.Raw) != 1 || quote.Raw["customPriceAlertConfidence"] != "HIGH" {
+		t.Errorf("Expected only the unmodeled field in Raw, got %v", quote.Raw)
+	}
+
+	if parseQuote(map[string]interface{}{"symbol": "AAPL"}).Raw != nil {
+		t.Error("Expected a nil Raw when every field is modeled")
+	}
+}
+
+func TestDefaultQuoteFields(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, field := range DefaultQuoteFields {
+		if seen[field] {
+			t.Errorf("Duplicate quote field %s", field)
+		}
+		seen[field] = true
+	}
+
+	// Every requested field must be parsed into Quote, not passed to Raw
+	for _, field := range DefaultQuoteFields {
+		modeled := false
+		for _, value := range []interface{}{float64(1700000000000), "x", true} {
+			quote := parseQuote(map[string]interface{}{field: value})
+			if quote.Raw != nil {
+				t.Errorf("Expected %s to be modeled, got Raw %v", field, quote.Raw)
+			}
+			if !reflect.DeepEqual(*quote, Quote{}) {
+				modeled = true
+			}
+		}
+		if !modeled {
+			t.Errorf("Expected %s to populate a Quote field", field)
+		}
+	}
+
+	// And every field parseQuote reads must be requested
+	quote := reflect.TypeOf(Quote{})
+	for i := 0; i < quote.NumField(); i++ {
+		tag := strings.Split(quote.Field(i).Tag.Get("json"), ",")[0]
+		switch tag {
+		case "raw", "firstTradeDate":
+			continue
+		}
+		if !seen[tag] {
+			t.Errorf("Quote.%s (%s) is not in DefaultQuoteFields", quote.Field(i).Name, tag)
+		}
+	}
+}
+
+func TestGetQuotesWithOptions(t *testing.T) {
+	var mu sync.Mutex
+	var batches []string
+	var fields string
+	mux := http.NewServeMux()
+	mux.Handle("/v1/test/getcrumb", fakeYahooHandler())
+	mux.HandleFunc("/v7/finance/quote", func(w http.ResponseWriter, r *http.Request) {
+		symbols := strings.Split(r.URL.Query().Get("symbols"), ",")
+		mu.Lock()
+		batches = append(batches, r.URL.Query().Get("symbols"))
+		fields = r.URL.Query().Get("fields")
+		mu.Unlock()
+
+		if slices.Contains(symbols, "FAIL") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var results []string
+		for _, symbol := range symbols {
+			if symbol != "NOPE" {
+				results = append(results, fmt.Sprintf(`{"symbol":%q,"regularMarketPrice":1.5}`, symbol))
+			}
+		}
+		fmt.Fprintf(w, `{"quoteResponse":{"result":[%s],"error":null}}`, strings.Join(results, ","))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {})
+	client := NewClient(WithHTTPClient(&handlerDoer{h: mux}), WithCacheDir(""))
+
+	result, err := client.GetQuotesWithOptions(context.Background(), []string{"aaa", "BBB", "NOPE", "CCC", "DDD", "AAA", " "}, &QuotesOptions{
+		Fields:    []string{"symbol", "regularMarketPrice"},
+		BatchSize: 2,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(batches) != 3 {
+		t.Errorf("Expected 3 batches of at most 2 symbols, got %v", batches)
+	}
+	if fields != "symbol,regularMarketPrice" {
+		t.Errorf("Expected the selected fields, got %s", fields)
+	}
+	if len(result.Quotes) != 4 || result.Quotes["AAA"] == nil || result.Quotes["DDD"].RegularMarketPrice != 1.5 {
+		t.Errorf("Expected quotes keyed by symbol, got %v", result.Quotes)
+	}
+	if len(result.Missing) != 1 || result.Missing[0] != "NOPE" {
+		t.Errorf("Expected NOPE to be missing, got %v", result.Missing)
+	}
+
+	quotes, err := client.GetQuotes(context.Background(), []string{"CCC", "NOPE", "AAA"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(quotes) != 2 || quotes[0].Symbol != "CCC" || quotes[1].Symbol != "AAA" {
+		t.Errorf("Expected quotes in request order, got %v", quotes)
+	}
+
+	// Symbols of a failed batch are neither quoted nor missing
+	result, err = client.GetQuotesWithOptions(context.Background(), []string{"AAA", "NOPE", "FAIL", "BBB"}, &QuotesOptions{
+		BatchSize: 2,
+	})
+	if err == nil {
+		t.Error("Expected the failed batch to be reported")
+	}
+	if len(result.Quotes) != 1 || result.Quotes["AAA"] == nil {
+		t.Errorf("Expected the quotes of the other batch, got %v", result.Quotes)
+	}
+	if len(result.Missing) != 1 || result.Missing[0] != "NOPE" {
+		t.Errorf("Expected only NOPE to be missing, got %v", result.Missing)
+	}
+}
+
+func TestSharedYfData(t *testing.T) {
+	retries := GlobalConfig.GetRetries()
+	defer GlobalConfig.SetRetries(retries)
+
+	data := sharedYfData()
+	if sharedYfData() != data {
+		t.Error("Expected the shared session to be reused")
+	}
+
+	GlobalConfig.SetRetries(retries + 1)
+	if sharedYfData() == data {
+		t.Error("Expected a new session after GlobalConfig changed")
+	}
+}
+
+// pricingData encodes a PricingData message for symbol the way Yahoo's
+// streamer does, with an unknown field to be skipped
+func pricingData(symbol string, price float32, marketHours uint64) []byte {
+	key := func(b []byte, field, wire uint64) []byte {
+		return binary.AppendUvarint(b, field<<3|wire)
+	}
+	zigzag := func(v int64) uint64 {
+		return uint64(v<<1) ^ uint64(v>>63)
+	}
+
+	var b []byte
+	b = key(b, 1, 2)
+	b = binary.AppendUvarint(b, uint64(len(symbol)))
+	b = append(b, symbol...)
+	b = key(b, 2, 5)
+	b = binary.LittleEndian.AppendUint32(b, math.Float32bits(price))
+	b = key(b, 3, 0)
+	b = binary.AppendUvarint(b, zigzag(1715371200000))
+	b = key(b, 6, 0)
+	b = binary.AppendUvarint(b, 8)
+	b = key(b, 7, 0)
+	b = binary.AppendUvarint(b, marketHours)
+	b = key(b, 9, 0)
+	b = binary.AppendUvarint(b, zigzag(50759500))
+	b = key(b, 12, 5)
+	b = binary.LittleEndian.AppendUint32(b, math.Float32bits(-1.52))
+	b = key(b, 99, 0)
+	b = binary.AppendUvarint(b, 42)
+	b = key(b, 23, 5)
+	b = binary.LittleEndian.AppendUint32(b, math.Float32bits(183.0))
+	b = key(b, 24, 0)
+	b = binary.AppendUvarint(b, zigzag(3))
+	b = key(b, 33, 1)
+	b = binary.LittleEndian.AppendUint64(b, math.Float64bits(2.8e12))
+	return b
+}
+
+// pricingFrame wraps a PricingData message in the version 2 envelope
+func pricingFrame(symbol string, price float32) string {
+	message := base64.StdEncoding.EncodeToString(pricingData(symbol, price, 1))
+	return fmt.Sprintf(`{"type":"pricing","message":%q}`, message)
+}
+
+func TestParseStreamerMessage(t *testing.T) {
+	tick, err := parseStreamerMessage(pricingFrame("AAPL", 183.05))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tick.Symbol != "AAPL" || tick.Price != 183.05 || tick.Change != -1.52 || tick.DayVolume != 50759500 {
+		t.Errorf("Unexpected tick: %+v", tick)
+	}
+	if tick.Time.UnixMilli() != 1715371200000 || tick.QuoteType != "EQUITY" || tick.MarketHours != SessionRegular {
+		t.Errorf("Unexpected time, quote type or market hours: %+v", tick)
+	}
+	if tick.Bid != 183 || tick.BidSize != 3 || tick.MarketCap != 2.8e12 {
+		t.Errorf("Unexpected bid or market cap: %+v", tick)
+	}
+
+	// Version 1 sends the message bare; pre-market is the protobuf default
+	tick, err = parseStreamerMessage(base64.StdEncoding.EncodeToString(pricingData("MSFT", 1, 0)))
+	if err != nil || tick.Symbol != "MSFT" || tick.MarketHours != SessionPre {
+		t.Errorf("Expected a pre-market MSFT tick, got %+v, %v", tick, err)
+	}
+
+	data := pricingData("MSFT", 1, 0)
+	if _, err := decodePricingData(data[:len(data)-3]); err == nil {
+		t.Error("Expected an error for a truncated message")
+	}
+}
+
+func TestStreamer(t *testing.T) {
+	type request struct {
+		conn    int
+		message map[string][]string
+	}
+	requests := make(chan request, 16)
+	var mu sync.Mutex
+	conns := 0
+	server := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		mu.Lock()
+		conns++
+		n := conns
+		mu.Unlock()
+
+		for {
+			var message map[string][]string
+			if err := websocket.JSON.Receive(ws, &message); err != nil {
+				return
+			}
+			requests <- request{n, message}
+			for _, symbol := range message["subscribe"] {
+				websocket.Message.Send(ws, "not base64")
+				websocket.Message.Send(ws, pricingFrame("NOPE", 1))
+				websocket.Message.Send(ws, pricingFrame(symbol, float32(n)))
+			}
+			// Drop the first connection to make the streamer reconnect
+			if n == 1 {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	var errs []error
+	streamer := NewStreamer(
+		WithStreamerURL("ws"+strings.TrimPrefix(server.URL, "http")),
+		WithReconnectPolicy(reconnectBackoff{base: 10 * time.Millisecond, max: 10 * time.Millisecond}),
+		WithStreamerErrorHandler(func(err error) { errs = append(errs, err) }),
+	)
+	if err := streamer.Subscribe("aaa"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ticks := streamer.Stream(ctx)
+	if _, ok := <-streamer.Stream(ctx); ok {
+		t.Error("Expected a second Stream call to return a closed channel")
+	}
+
+	expect := func(conn int, action, symbol string) {
+		t.Helper()
+		select {
+		case r := <-requests:
+			if r.conn != conn || len(r.message[action]) != 1 || r.message[action][0] != symbol {
+				t.Errorf("Expected %s %s on connection %d, got %+v", action, symbol, conn, r)
+			}
+		case <-ctx.Done():
+			t.Fatalf("Timed out waiting for %s %s", action, symbol)
+		}
+	}
+	expectTick := func(symbol string, price float64) {
+		t.Helper()
+		tick, ok := <-ticks
+		if !ok || tick.Symbol != symbol || tick.Price != price {
+			t.Errorf("Expected a %s tick at %v, got %+v", symbol, price, tick)
+		}
+	}
+
+	expect(1, "subscribe", "AAA")
+	expectTick("AAA", 1)
+	expect(2, "subscribe", "AAA") // resubscribed after reconnecting
+	expectTick("AAA", 2)
+
+	if err := streamer.Subscribe("BBB"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expect(2, "subscribe", "BBB")
+	expectTick("BBB", 2)
+
+	if err := streamer.Unsubscribe("AAA"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expect(2, "unsubscribe", "AAA")
+	if symbols := streamer.Symbols(); len(symbols) != 1 || symbols[0] != "BBB" {
+		t.Errorf("Expected only BBB to be subscribed, got %v", symbols)
+	}
+
+	cancel()
+	for range ticks {
+	}
+	if len(errs) == 0 {
+		t.Error("Expected decoding and connection errors to be reported")
+	}
+}
+
+func TestClientStreamer(t *testing.T) {
+	headers := make(chan http.Header, 1)
+	mux := http.NewServeMux()
+	mux.Handle("/streamer", websocket.Handler(func(ws *websocket.Conn) {
+		headers <- ws.Request().Header
+		var message map[string][]string
+		for websocket.JSON.Receive(ws, &message) == nil {
+			for _, symbol := range message["subscribe"] {
+				websocket.Message.Send(ws, pricingFrame(symbol, 7))
+			}
+		}
+	}))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	// A CONNECT proxy that records the hosts it tunnels to
+	proxy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer proxy.Close()
+	tunnels := make(chan string, 1)
+	go func() {
+		for {
+			conn, err := proxy.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				req, err := http.ReadRequest(bufio.NewReader(conn))
+				if err != nil || req.Method != http.MethodConnect {
+					return
+				}
+				tunnels <- req.Host
+				upstream, err := net.Dial("tcp", req.Host)
+				if err != nil {
+					return
+				}
+				defer upstream.Close()
+				fmt.Fprint(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+				go io.Copy(upstream, conn)
+				io.Copy(conn, upstream)
+			}()
+		}
+	}()
+
+	client := NewClient(
+		WithCacheDir(""),
+		WithProxy("http://"+proxy.Addr().String()),
+		WithUserAgent("yfinance-test"),
+		WithEndpoints(LocalEndpoints(server.URL)),
+	)
+	streamer := client.Streamer()
+	streamer.Subscribe("AAA")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tick, ok := <-streamer.Stream(ctx)
+	if !ok || tick.Symbol != "AAA" || tick.Price != 7 {
+		t.Fatalf("Expected an AAA tick, got %+v", tick)
+	}
+
+	if host := <-tunnels; host != strings.TrimPrefix(server.URL, "http://") {
+		t.Errorf("Expected the proxy to tunnel to the streamer, got %s", host)
+	}
+	header := <-headers
+	if ua := header.Get("User-Agent"); ua != "yfinance-test" {
+		t.Errorf("Expected the client's user agent, got %q", ua)
+	}
+	if origin := header.Get("Origin"); origin != server.URL {
+		t.Errorf("Expected the client's root endpoint as origin, got %q", origin)
+	}
+}
+
 // Integration tests (require network)
 // These tests are skipped by default, use -tags=integration to run
 
@@ -0,0 +1,563 @@
+// Package yfinancetest provides an in-process fake Yahoo Finance server for
+// deterministic tests of code built on yfinance.
+//
+// The server emulates the chart, spark, quote, quoteSummary, search and
+// news endpoints as well as the cookie, crumb and consent negotiation. Responses
+// are served from fixture files, and faults such as rate limiting or
+// consent redirects can be injected to exercise retry paths.
+//
+// Basic Usage:
+//
+//	server := yfinancetest.NewServer()
+//	defer server.Close()
+//
+//	client := server.Client()
+//	history, err := client.Ticker("AAPL").History(ctx, nil)
+package yfinancetest
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	yf "github.com/FFengIll/yfinance-go"
+)
+
+//go:embed fixtures
+var defaultFixtures embed.FS
+
+// DefaultCrumb is the crumb handed out by the server unless overridden
+const DefaultCrumb = "yfinancetest-crumb"
+
+// Fault is an error condition the server can be told to return
+type Fault int
+
+const (
+	// RateLimited answers with 429 Too Many Requests
+	RateLimited Fault = iota
+	// Unauthorized answers with 401 Invalid Crumb
+	Unauthorized
+	// ConsentRedirect redirects to the consent page
+	ConsentRedirect
+	// Down answers with Yahoo's "Will be right back" page
+	Down
+)
+
+// String returns the name of the fault
+func (f Fault) String() string {
+	switch f {
+	case RateLimited:
+		return "RateLimited"
+	case Unauthorized:
+		return "Unauthorized"
+	case ConsentRedirect:
+		return "ConsentRedirect"
+	case Down:
+		return "Down"
+	}
+	return fmt.Sprintf("Fault(%d)", int(f))
+}
+
+// Server is a fake Yahoo Finance server
+type Server struct {
+	*httptest.Server
+
+	fixtures        fs.FS
+	crumb           string
+	consentRequired bool
+
+	mu       sync.Mutex
+	faults   []injectedFault
+	requests []string
+}
+
+// injectedFault is a fault waiting for a request under pathPrefix
+type injectedFault struct {
+	pathPrefix string
+	fault      Fault
+}
+
+// Option is a functional option for Server
+type Option func(*Server)
+
+// WithFixtures serves responses from fsys instead of the built-in fixtures.
+//
+// The layout is:
+//
+//	chart/<SYMBOL>_<interval>.json  chart response for one interval
+//	chart/<SYMBOL>.json             chart response for any interval
+//	                                (spark responses are built from charts)
+//	quote/<SYMBOL>.json             single quote result object
+//	quoteSummary/<SYMBOL>.json      quoteSummary response
+//	search/<query>.json             search response (query lowercased)
+//	news/<SYMBOL>.json              news (xhr/ncp) response
+func WithFixtures(fsys fs.FS) Option {
+	return func(s *Server) {
+		s.fixtures = fsys
+	}
+}
+
+// WithCrumb sets the crumb the server hands out and expects
+func WithCrumb(crumb string) Option {
+	return func(s *Server) {
+		s.crumb = crumb
+	}
+}
+
+// WithConsentRequired makes the getcrumb endpoint refuse to issue a crumb
+// until consent was collected, like Yahoo does for EU visitors. This forces
+// clients onto the CSRF cookie strategy.
+func WithConsentRequired() Option {
+	return func(s *Server) {
+		s.consentRequired = true
+	}
+}
+
+// NewServer starts a fake Yahoo Finance server. The caller must Close it.
+func NewServer(opts ...Option) *Server {
+	fixtures, _ := fs.Sub(defaultFixtures, "fixtures")
+	s := &Server{
+		fixtures: fixtures,
+		crumb:    DefaultCrumb,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/fc", s.handleCookie)
+	mux.HandleFunc("/guce/consent", s.handleConsentPage)
+	mux.HandleFunc("/guce/copyConsent", s.handleCopyConsent)
+	mux.HandleFunc("/consent/v2/collectConsent", s.handleCollectConsent)
+	mux.HandleFunc("/v1/test/getcrumb", s.handleCrumb)
+	mux.HandleFunc("/v8/finance/chart/", s.requireCrumb(s.handleChart))
+	mux.HandleFunc("/v7/finance/spark", s.requireCrumb(s.handleSpark))
+	mux.HandleFunc("/v7/finance/quote", s.requireCrumb(s.handleQuote))
+	mux.HandleFunc("/v10/finance/quoteSummary/", s.requireCrumb(s.handleQuoteSummary))
+	mux.HandleFunc("/v1/finance/search", s.requireCrumb(s.handleSearch))
+	mux.HandleFunc("/xhr/ncp", s.handleNews)
+
+	s.Server = httptest.NewServer(s.withFaults(mux))
+	return s
+}
+
+// Endpoints returns endpoints pointing every Yahoo host at the server
+func (s *Server) Endpoints() yf.Endpoints {
+	return yf.LocalEndpoints(s.URL)
+}
+
+// Client returns a yfinance Client wired to the server, with the on-disk
+// cookie cache disabled. Additional options are applied afterwards.
+func (s *Server) Client(opts ...yf.ClientOption) *yf.Client {
+	base := []yf.ClientOption{
+		yf.WithHTTPClient(s.Server.Client()),
+		yf.WithEndpoints(s.Endpoints()),
+		yf.WithCacheDir(""),
+	}
+	return yf.NewClient(append(base, opts...)...)
+}
+
+// Inject queues faults for requests whose path starts with pathPrefix.
+// Each fault is consumed by exactly one request, in order.
+func (s *Server) Inject(pathPrefix string, faults ...Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, f := range faults {
+		s.faults = append(s.faults, injectedFault{pathPrefix: pathPrefix, fault: f})
+	}
+}
+
+// Requests returns the paths of every request received so far
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// RequestCount returns how many requests were received under pathPrefix
+func (s *Server) RequestCount(pathPrefix string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, p := range s.requests {
+		if strings.HasPrefix(p, pathPrefix) {
+			n++
+		}
+	}
+	return n
+}
+
+// Reset clears the request log and any pending faults
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = nil
+	s.faults = nil
+}
+
+// withFaults logs every request and serves pending faults before routing
+func (s *Server) withFaults(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, r.URL.Path)
+		fault, ok := s.nextFault(r.URL.Path)
+		s.mu.Unlock()
+
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		switch fault {
+		case RateLimited:
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+		case Unauthorized:
+			writeFinanceError(w, http.StatusUnauthorized, "Unauthorized", "Invalid Crumb")
+		case ConsentRedirect:
+			http.Redirect(w, r, s.URL+"/guce/consent?sessionId=yfinancetest", http.StatusFound)
+		case Down:
+			w.Header().Set("Content-Type", "text/html")
+			io.WriteString(w, "<html><body><h1>Will be right back...</h1></body></html>")
+		}
+	})
+}
+
+// nextFault pops the first fault matching path (must be called with lock held)
+func (s *Server) nextFault(p string) (Fault, bool) {
+	for i, f := range s.faults {
+		if strings.HasPrefix(p, f.pathPrefix) {
+			s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			return f.fault, true
+		}
+	}
+	return 0, false
+}
+
+// requireCrumb rejects API requests that do not carry the issued crumb
+func (s *Server) requireCrumb(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("crumb") != s.crumb {
+			writeFinanceError(w, http.StatusUnauthorized, "Unauthorized", "Invalid Crumb")
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) handleCookie(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{Name: "A3", Value: "yfinancetest-a3", Path: "/"})
+	http.NotFound(w, r)
+}
+
+func (s *Server) handleConsentPage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html")
+	fmt.Fprint(w, `<html><body><form method="post">`+
+		`<input type="hidden" name="csrfToken" value="yfinancetest-csrf">`+
+		`<input type="hidden" name="sessionId" value="yfinancetest">`+
+		`</form></body></html>`)
+}
+
+func (s *Server) handleCollectConsent(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.Form.Get("csrfToken") != "yfinancetest-csrf" {
+		http.Error(w, "bad consent form", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: "GUC", Value: "consented", Path: "/"})
+}
+
+func (s *Server) handleCopyConsent(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) handleCrumb(w http.ResponseWriter, r *http.Request) {
+	if s.consentRequired {
+		if _, err := r.Cookie("GUC"); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}
+	io.WriteString(w, s.crumb)
+}
+
+func (s *Server) handleChart(w http.ResponseWriter, r *http.Request) {
+	symbol := path.Base(r.URL.Path)
+	interval := r.URL.Query().Get("interval")
+
+	for _, name := range []string{
+		fmt.Sprintf("chart/%s_%s.json", symbol, interval),
+		fmt.Sprintf("chart/%s.json", symbol),
+	} {
+		if data, err := fs.ReadFile(s.fixtures, name); err == nil {
+			writeJSONBytes(w, filterChart(data, r.URL.Query()))
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotFound)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"chart": map[string]interface{}{
+			"result": nil,
+			"error": map[string]string{
+				"code":        "Not Found",
+				"description": "No data found, symbol may be delisted",
+			},
+		},
+	})
+}
+
+// handleSpark answers with the closes of the chart fixture of every
+// requested symbol. Like Yahoo, it omits unknown symbols and refuses more
+// than yf.SparkBatchSize symbols.
+func (s *Server) handleSpark(w http.ResponseWriter, r *http.Request) {
+	symbols := strings.Split(r.URL.Query().Get("symbols"), ",")
+	if len(symbols) > yf.SparkBatchSize {
+		writeFinanceError(w, http.StatusBadRequest, "Bad Request", "too many symbols")
+		return
+	}
+	interval := r.URL.Query().Get("interval")
+
+	results := make([]interface{}, 0, len(symbols))
+	for _, symbol := range symbols {
+		symbol = strings.TrimSpace(symbol)
+		var data []byte
+		for _, name := range []string{
+			fmt.Sprintf("chart/%s_%s.json", symbol, interval),
+			fmt.Sprintf("chart/%s.json", symbol),
+		} {
+			if fixture, err := fs.ReadFile(s.fixtures, name); err == nil {
+				data = fixture
+				break
+			}
+		}
+		if data == nil {
+			continue
+		}
+
+		var chart struct {
+			Chart struct {
+				Result []struct {
+					Meta       json.RawMessage `json:"meta"`
+					Timestamp  []int64         `json:"timestamp"`
+					Indicators struct {
+						Quote []struct {
+							Close []*float64 `json:"close"`
+						} `json:"quote"`
+					} `json:"indicators"`
+				} `json:"result"`
+			} `json:"chart"`
+		}
+		if err := json.Unmarshal(data, &chart); err != nil || len(chart.Chart.Result) == 0 {
+			continue
+		}
+		result := chart.Chart.Result[0]
+		var closes []*float64
+		if len(result.Indicators.Quote) > 0 {
+			closes = result.Indicators.Quote[0].Close
+		}
+		results = append(results, map[string]interface{}{
+			"symbol": symbol,
+			"response": []interface{}{map[string]interface{}{
+				"meta":      result.Meta,
+				"timestamp": result.Timestamp,
+				"indicators": map[string]interface{}{
+					"quote": []interface{}{map[string]interface{}{"close": closes}},
+				},
+			}},
+		})
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"spark": map[string]interface{}{
+			"result": results,
+			"error":  nil,
+		},
+	})
+}
+
+// filterChart drops the bars and events of a chart response that fall
+// outside the period1/period2 query parameters, like Yahoo does
+func filterChart(data []byte, query url.Values) []byte {
+	from, hasFrom := parseUnix(query.Get("period1"))
+	to, hasTo := parseUnix(query.Get("period2"))
+	if !hasFrom && !hasTo {
+		return data
+	}
+	inRange := func(ts int64) bool {
+		return (!hasFrom || ts >= from) && (!hasTo || ts < to)
+	}
+
+	// Bodies that are not chart results, such as errors, pass through
+	var chart map[string]interface{}
+	if err := json.Unmarshal(data, &chart); err != nil {
+		return data
+	}
+	body, ok := chart["chart"].(map[string]interface{})
+	if !ok {
+		return data
+	}
+	results, ok := body["result"].([]interface{})
+	if !ok {
+		return data
+	}
+	for _, r := range results {
+		result, ok := r.(map[string]interface{})
+		if !ok {
+			return data
+		}
+		timestamps, _ := result["timestamp"].([]interface{})
+
+		keep := make([]bool, len(timestamps))
+		var kept []interface{}
+		for i, ts := range timestamps {
+			if v, ok := ts.(float64); ok && inRange(int64(v)) {
+				keep[i] = true
+				kept = append(kept, ts)
+			}
+		}
+		result["timestamp"] = kept
+
+		indicators, _ := result["indicators"].(map[string]interface{})
+		for _, series := range indicators {
+			list, _ := series.([]interface{})
+			for _, item := range list {
+				columns, _ := item.(map[string]interface{})
+				for name, column := range columns {
+					values, _ := column.([]interface{})
+					var filtered []interface{}
+					for i, v := range values {
+						if i < len(keep) && keep[i] {
+							filtered = append(filtered, v)
+						}
+					}
+					columns[name] = filtered
+				}
+			}
+		}
+
+		events, _ := result["events"].(map[string]interface{})
+		for _, kind := range events {
+			byDate, _ := kind.(map[string]interface{})
+			for key := range byDate {
+				if ts, ok := parseUnix(key); ok && !inRange(ts) {
+					delete(byDate, key)
+				}
+			}
+		}
+	}
+
+	filtered, err := json.Marshal(chart)
+	if err != nil {
+		return data
+	}
+	return filtered
+}
+
+// parseUnix parses a Unix timestamp in seconds
+func parseUnix(s string) (int64, bool) {
+	ts, err := strconv.ParseInt(s, 10, 64)
+	return ts, err == nil
+}
+
+func (s *Server) handleQuote(w http.ResponseWriter, r *http.Request) {
+	results := make([]json.RawMessage, 0)
+	for _, symbol := range strings.Split(r.URL.Query().Get("symbols"), ",") {
+		symbol = strings.TrimSpace(symbol)
+		if symbol == "" {
+			continue
+		}
+		data, err := fs.ReadFile(s.fixtures, fmt.Sprintf("quote/%s.json", symbol))
+		if err != nil {
+			// Yahoo silently omits unknown symbols
+			continue
+		}
+		results = append(results, json.RawMessage(data))
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"quoteResponse": map[string]interface{}{
+			"result": results,
+			"error":  nil,
+		},
+	})
+}
+
+func (s *Server) handleQuoteSummary(w http.ResponseWriter, r *http.Request) {
+	symbol := path.Base(r.URL.Path)
+	data, err := fs.ReadFile(s.fixtures, fmt.Sprintf("quoteSummary/%s.json", symbol))
+	if err != nil {
+		writeFinanceError(w, http.StatusNotFound, "Not Found", "Quote not found for symbol: "+symbol)
+		return
+	}
+	writeJSONBytes(w, data)
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := strings.ToLower(r.URL.Query().Get("q"))
+	data, err := fs.ReadFile(s.fixtures, fmt.Sprintf("search/%s.json", query))
+	if err != nil {
+		writeJSON(w, map[string]interface{}{
+			"quotes": []interface{}{},
+			"news":   []interface{}{},
+			"lists":  []interface{}{},
+		})
+		return
+	}
+	writeJSONBytes(w, data)
+}
+
+func (s *Server) handleNews(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		ServiceConfig struct {
+			S []string `json:"s"`
+		} `json:"serviceConfig"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	if len(body.ServiceConfig.S) > 0 {
+		name := fmt.Sprintf("news/%s.json", body.ServiceConfig.S[0])
+		if data, err := fs.ReadFile(s.fixtures, name); err == nil {
+			writeJSONBytes(w, data)
+			return
+		}
+	}
+	writeJSON(w, map[string]interface{}{
+		"data": map[string]interface{}{
+			"tickerStream": map[string]interface{}{"stream": []interface{}{}},
+		},
+	})
+}
+
+// writeFinanceError writes an error in the format used by Yahoo's finance APIs
+func writeFinanceError(w http.ResponseWriter, status int, code, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"finance": map[string]interface{}{
+			"result": nil,
+			"error": map[string]string{
+				"code":        code,
+				"description": description,
+			},
+		},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeJSONBytes(w http.ResponseWriter, data []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
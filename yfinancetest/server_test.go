@@ -0,0 +1,211 @@
+package yfinancetest_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	yf "github.com/FFengIll/yfinance-go"
+	"github.com/FFengIll/yfinance-go/yfinancetest"
+)
+
+func TestHistory(t *testing.T) {
+	server := yfinancetest.NewServer()
+	defer server.Close()
+
+	history, err := server.Client().Ticker("AAPL").History(context.Background(), &yf.HistoryOptions{
+		Period:   "5d",
+		Interval: "1d",
+	})
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+
+	if history.Meta.Symbol != "AAPL" {
+		t.Errorf("Expected symbol AAPL, got %s", history.Meta.Symbol)
+	}
+	if len(history.Data) != 5 {
+		t.Fatalf("Expected 5 bars, got %d", len(history.Data))
+	}
+	if history.Data[4].Close != 183.05 {
+		t.Errorf("Expected last close 183.05, got %f", history.Data[4].Close)
+	}
+}
+
+func TestChartFixturePassthrough(t *testing.T) {
+	fixture := `{"finance":{"result":null,"error":{"code":"Not Found","description":"No data found"}}}`
+	server := yfinancetest.NewServer(yfinancetest.WithFixtures(fstest.MapFS{
+		"chart/AAPL.json": {Data: []byte(fixture)},
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/v8/finance/chart/AAPL?period1=0&period2=1&crumb=" + yfinancetest.DefaultCrumb)
+	if err != nil {
+		t.Fatalf("Failed to get chart: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if strings.TrimSpace(string(body)) != fixture {
+		t.Errorf("Expected the fixture unchanged, got %s", body)
+	}
+}
+
+func TestDownloadCloseOnly(t *testing.T) {
+	server := yfinancetest.NewServer()
+	defer server.Close()
+
+	result, err := server.Client().Download(context.Background(), &yf.DownloadOptions{
+		Tickers:   []string{"AAPL", "MSFT", "NOPE"},
+		Period:    "5d",
+		Interval:  "1d",
+		CloseOnly: true,
+		Threads:   2,
+	})
+	if err != nil {
+		t.Fatalf("Failed to download: %v", err)
+	}
+
+	if n := server.RequestCount("/v7/finance/spark"); n != 1 {
+		t.Errorf("Expected 1 spark request, got %d", n)
+	}
+	if n := server.RequestCount("/v8/finance/chart/AAPL") + server.RequestCount("/v8/finance/chart/MSFT"); n != 0 {
+		t.Errorf("Expected no chart requests for symbols in the spark response, got %d", n)
+	}
+	if n := server.RequestCount("/v8/finance/chart/NOPE"); n == 0 {
+		t.Error("Expected NOPE to fall back to a chart request")
+	}
+
+	history := result.Data["AAPL"]
+	if history == nil || len(history.Data) != 5 {
+		t.Fatalf("Expected 5 AAPL bars, got %+v", history)
+	}
+	if bar := history.Data[4]; bar.Close != 183.05 || !bar.IsMissing(yf.FieldOpen|yf.FieldAdjClose|yf.FieldVolume) {
+		t.Errorf("Expected a close-only bar, got %+v", bar)
+	}
+	if len(result.Failed) != 1 || result.Failed[0] != "NOPE" {
+		t.Errorf("Expected NOPE to fail, got %v", result.Failed)
+	}
+}
+
+func TestGetQuotes(t *testing.T) {
+	server := yfinancetest.NewServer()
+	defer server.Close()
+
+	quotes, err := server.Client().GetQuotes(context.Background(), []string{"AAPL", "MSFT", "NOPE"})
+	if err != nil {
+		t.Fatalf("Failed to get quotes: %v", err)
+	}
+
+	if len(quotes) != 2 {
+		t.Fatalf("Expected 2 quotes, got %d", len(quotes))
+	}
+	if quotes[0].Symbol != "AAPL" || quotes[0].RegularMarketPrice != 183.05 {
+		t.Errorf("Unexpected AAPL quote: %+v", quotes[0])
+	}
+
+	quote, err := server.Client().Ticker("AAPL").GetQuote(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get quote: %v", err)
+	}
+	if !reflect.DeepEqual(quote, quotes[0]) {
+		t.Errorf("Expected GetQuote and GetQuotes to agree, got %+v and %+v", quote, quotes[0])
+	}
+	if quote.Exchange != "NMS" || quote.FullExchangeName != "NasdaqGS" || quote.MarketState != "CLOSED" {
+		t.Errorf("Unexpected exchange fields: %+v", quote)
+	}
+	if quote.Bid != 183.0 || quote.AskSize != 2 || quote.AverageDailyVolume3Month != 58000000 {
+		t.Errorf("Unexpected order book or volume fields: %+v", quote)
+	}
+	if quote.DividendDate.Unix() != 1715817600 || quote.EarningsTimestampEnd.Unix() != 1722513600 {
+		t.Errorf("Unexpected event timestamps: %+v", quote)
+	}
+	if quote.Raw["customPriceAlertConfidence"] != "HIGH" {
+		t.Errorf("Expected unmodeled fields in Raw, got %v", quote.Raw)
+	}
+	if _, ok := quote.Raw["symbol"]; ok {
+		t.Error("Expected modeled fields to be left out of Raw")
+	}
+}
+
+func TestSearch(t *testing.T) {
+	server := yfinancetest.NewServer()
+	defer server.Close()
+
+	search := server.Client().Search("Apple")
+	if err := search.Do(context.Background()); err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+
+	quotes := search.Quotes()
+	if len(quotes) == 0 || quotes[0].Symbol != "AAPL" {
+		t.Errorf("Expected AAPL as the first search result, got %+v", quotes)
+	}
+	if len(search.News()) != 1 {
+		t.Errorf("Expected 1 news item, got %d", len(search.News()))
+	}
+}
+
+func TestConsentRequired(t *testing.T) {
+	server := yfinancetest.NewServer(yfinancetest.WithConsentRequired())
+	defer server.Close()
+
+	if _, err := server.Client().Ticker("AAPL").GetInfo(context.Background()); err != nil {
+		t.Fatalf("Failed to get info: %v", err)
+	}
+	if server.RequestCount("/consent/v2/collectConsent") != 1 {
+		t.Error("Expected the client to fall back to the consent flow")
+	}
+}
+
+func TestFaults(t *testing.T) {
+	tests := []struct {
+		name  string
+		fault yfinancetest.Fault
+	}{
+		{"rate limited", yfinancetest.RateLimited},
+		{"unauthorized", yfinancetest.Unauthorized},
+		{"consent redirect", yfinancetest.ConsentRedirect},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := yfinancetest.NewServer()
+			defer server.Close()
+
+			server.Inject("/v7/finance/quote", tt.fault)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			quote, err := server.Client().Ticker("MSFT").GetQuote(ctx)
+			if err != nil {
+				t.Fatalf("Expected the request to be retried, got %v", err)
+			}
+			if quote.Symbol != "MSFT" {
+				t.Errorf("Expected symbol MSFT, got %s", quote.Symbol)
+			}
+			if n := server.RequestCount("/v7/finance/quote"); n != 2 {
+				t.Errorf("Expected 2 quote requests, got %d", n)
+			}
+		})
+	}
+}
+
+func TestDown(t *testing.T) {
+	server := yfinancetest.NewServer()
+	defer server.Close()
+
+	server.Inject("/v8/finance/chart/", yfinancetest.Down)
+
+	_, err := server.Client().Ticker("AAPL").GetTimezone(context.Background())
+	var dataErr *yf.YFDataException
+	if !errors.As(err, &dataErr) {
+		t.Errorf("Expected YFDataException, got %v", err)
+	}
+}